@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+// runCommand runs the CLI subcommand on args, the arguments after the program name. The commands are under the names
+// command, such as names import <file>; the names prefix is optional, so the binary built as names runs them directly.
+func runCommand(args []string) error {
+	if args[0] == "names" {
+		args = args[1:]
+		if len(args) == 0 {
+			return errors.New("usage: names import|migrate ...")
+		}
+	}
+
+	switch args[0] {
+	case "import":
+		return importCommand(args[1:])
+	case "migrate":
+		return migrateCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// importCommand imports the names of a CSV or NDJSON file and prints the import report.
+// Usage: names import [-mode skip|upsert|replace] [-format csv|ndjson] [-dry-run] <file>
+func importCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	mode := fs.String("mode", database.ImportSkip, "what happens to the names already on the table: skip, upsert or replace")
+	format := fs.String("format", "", "format of the file: csv or ndjson, by default the one of the file extension")
+	dryRun := fs.Bool("dry-run", false, "report what the import would do without writing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: names import [-mode skip|upsert|replace] [-format csv|ndjson] [-dry-run] <file>")
+	}
+
+	connect()
+
+	fileName := fs.Arg(0)
+	opts := database.ImportOptions{Format: *format, Mode: *mode, DryRun: *dryRun, RecordChanges: true}
+	if opts.Format == "" {
+		opts.Format = database.FormatOf(fileName)
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	defer f.Close()
+
+	report, importErr := database.ImportNames(models.DB, f, opts)
+
+	// Print the report, even of a failed import, so the rows already imported are known
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	if err := out.Encode(report); err != nil {
+		return fmt.Errorf("error printing report: %w", err)
+	}
+
+	if importErr != nil {
+		return importErr
+	}
+	if report.Failed != 0 {
+		return fmt.Errorf("%d rows could not be imported", report.Failed)
+	}
+	return nil
+}
+
+// migrateCommand applies, reverts or lists the migrations of the database schema.
+// Usage: names migrate up | names migrate down [-steps N] | names migrate status
+func migrateCommand(args []string) error {
+	usage := errors.New("usage: names migrate up | names migrate down [-steps N] | names migrate status")
+	if len(args) == 0 {
+		return usage
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := fs.Int("steps", 1, "number of migrations reverted by down")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *steps < 1 {
+		return usage
+	}
+
+	db, err := database.OpenDB()
+	if err != nil {
+		return fmt.Errorf("error connecting to the database: %w", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migrations applied\n", len(applied))
+	case "down":
+		reverted, err := database.MigrateDown(db, *steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migrations reverted\n", len(reverted))
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			status := "pending"
+			if s.Applied {
+				status = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-32s  %s\n", s.Version, s.Name, status)
+		}
+	default:
+		return usage
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+// setupCommandDB prepares the test database for connect: migrated, with the root user and the seed already recorded
+func setupCommandDB(t *testing.T) {
+	t.Helper()
+	db := dbtest.Open(t)
+	t.Setenv("DB_AUTO_MIGRATE", "false")
+	t.Setenv("SECRET", "test-secret")
+	t.Setenv("JWT_ALGORITHM", "")
+	if err := db.Create(&models.Seed{File: database.SeedFile}).Error; err != nil {
+		t.Fatalf("error recording seed: %v", err)
+	}
+	root := models.User{Email: "root@root.com", Password: "hash", Role: models.RoleAdmin}
+	if _, err := root.CreateUser(); err != nil {
+		t.Fatalf("error creating root: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := models.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// runImportCommand runs names import with the args and returns the report it prints
+func runImportCommand(t *testing.T, args ...string) (database.ImportReport, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("error creating pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	cmdErr := runCommand(append([]string{"names", "import"}, args...))
+	os.Stdout = stdout
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("error reading output: %v", err)
+	}
+	var report database.ImportReport
+	if err = json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("error decoding report %q: %v", out, err)
+	}
+	return report, cmdErr
+}
+
+func TestImportCommand(t *testing.T) {
+	setupCommandDB(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "names.csv")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	write("name,classification,name_variations\nANA,F,ANNA\nJOAO,M,\n")
+	report, err := runImportCommand(t, file)
+	if err != nil || report.Inserted != 2 || report.Mode != database.ImportSkip {
+		t.Fatalf("import = %+v, %v, want 2 inserted in skip mode", report, err)
+	}
+
+	write("name,classification,name_variations\nANA,M,\nJOAO,F,\nPEDRO,M,\n")
+	tests := []struct {
+		mode                       string
+		inserted, updated, skipped int
+		classification             string
+	}{
+		{database.ImportSkip, 1, 0, 2, "F"},
+		{database.ImportUpsert, 0, 3, 0, "M"},
+	}
+	for _, tt := range tests {
+		report, err = runImportCommand(t, "-mode="+tt.mode, file)
+		if err != nil {
+			t.Fatalf("import -mode=%s error: %v", tt.mode, err)
+		}
+		if report.Rows != 3 || report.Inserted != tt.inserted || report.Updated != tt.updated || report.Skipped != tt.skipped {
+			t.Fatalf("import -mode=%s = %+v, want %d inserted, %d updated and %d skipped of 3 rows", tt.mode, report, tt.inserted, tt.updated, tt.skipped)
+		}
+		var ana models.NameType
+		if err = models.DB.Where("name = ?", "ANA").First(&ana).Error; err != nil || ana.Classification != tt.classification {
+			t.Fatalf("ANA classification after -mode=%s = %q, %v, want %q", tt.mode, ana.Classification, err, tt.classification)
+		}
+	}
+
+	// A dry run changes nothing, and the failed rows fail the command after the report is printed
+	write("name,classification\nMARIA,F\nJOSE,Z\n")
+	report, err = runImportCommand(t, "-mode=upsert", "-dry-run", file)
+	if err == nil || report.Inserted != 1 || report.Failed != 1 || !report.DryRun {
+		t.Fatalf("dry run = %+v, %v, want 1 inserted, 1 failed and an error", report, err)
+	}
+	var count int64
+	models.DB.Model(&models.NameType{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("%d names on the table after the dry run, want 3", count)
+	}
+}
+
+func TestImportCommandUsage(t *testing.T) {
+	for _, args := range [][]string{{"names"}, {"names", "import"}, {"names", "seed"}} {
+		if err := runCommand(args); err == nil {
+			t.Errorf("runCommand(%v) succeeded, want an error", args)
+		}
+	}
+}
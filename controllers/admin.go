@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCache reads the version of the name cache
+func GetCache(c *gin.Context) {
+	// The cache is passed by middlewares
+	cache, ok := getNameCache(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting cache from middlewares"})
+		return
+	}
+
+	snapshot, err := cache.Snapshot()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on loading cache"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ReloadCache loads the names from the database and swaps the name cache
+func ReloadCache(c *gin.Context) {
+	// The cache is passed by middlewares
+	cache, ok := getNameCache(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting cache from middlewares"})
+		return
+	}
+
+	snapshot, err := cache.Reload()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on reloading cache"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, gin.H{"Message": "Cache reloaded", "Cache": snapshot})
+}
+
+// ImportNames imports the names of the uploaded CSV or NDJSON file and returns the import report
+func ImportNames(c *gin.Context) {
+	// The file and the options are passed by middlewares
+	fileValue, _ := c.Get("importFile")
+	optsValue, _ := c.Get("importOptions")
+	file, okFile := fileValue.(*multipart.FileHeader)
+	opts, okOpts := optsValue.(database.ImportOptions)
+	if !okFile || !okOpts {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting import from middlewares"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on opening file"})
+		return
+	}
+	defer f.Close()
+
+	report, err := database.ImportNames(models.DB, f, opts)
+
+	// Patch the cache with the batches already imported, even if a later one failed
+	if !opts.DryRun && report.Inserted+report.Updated != 0 {
+		if cache, ok := getNameCache(c); ok {
+			if _, err := cache.Sync(); err != nil {
+				log.Printf("Error syncing name cache: %v", err)
+			}
+		}
+	}
+
+	if errors.Is(err, database.ErrInvalidFile) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "Report": report})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on importing names", "Report": report})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, report)
+}
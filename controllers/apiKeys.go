@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKey creates an API key of the authenticated user. The key is only returned here, it can't be read again.
+func CreateAPIKey(c *gin.Context) {
+	// The name and scope are passed by middlewares
+	value, _ := c.Get("apiKeyInput")
+	input, ok := value.(models.APIKeyInput)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting API key from middlewares"})
+		return
+	}
+
+	// A key can't grant more than the role of its user
+	if input.Scope == models.ScopeWrite && !models.RoleAllows(c.GetString("userRole"), models.RoleEditor) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "a write key requires the editor role"})
+		return
+	}
+
+	// Get the authenticated user
+	u, err := models.GetUserByID(c.GetUint("userID"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	apiKey, key, err := u.CreateAPIKey(input.Name, input.Scope)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on creating API key"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, gin.H{"Message": "API key created, it won't be shown again", "Key": key, "APIKey": apiKey})
+}
+
+// GetAPIKeys reads the API keys of the authenticated user
+func GetAPIKeys(c *gin.Context) {
+	keys, err := models.GetAPIKeys(c.GetUint("userID"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting API keys"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey revokes the API key with the given id. Users revoke their own keys, admins revoke any key.
+func RevokeAPIKey(c *gin.Context) {
+	// The id is validated by middlewares
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id parameter, it must be a valid integer"})
+		return
+	}
+
+	// Keys of other users are not found, unless the user is an admin
+	apiKey, err := models.GetAPIKeyByID(uint(id))
+	if err != nil || (apiKey.UserID != c.GetUint("userID") && !models.RoleAllows(c.GetString("userRole"), models.RoleAdmin)) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	err = apiKey.Revoke()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on revoking API key"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, gin.H{"Message": "API key revoked", "APIKey": apiKey})
+}
@@ -1,13 +1,12 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
 	"github.com/Darklabel91/API_Names/models"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 )
 
 //CreateName creates a new name in the database of type NameType
@@ -30,15 +29,11 @@ func CreateName(c *gin.Context) {
 	preloadTable := checkCache(c)
 
 	// Check if there's an exact name on the database
-	for _, name := range preloadTable {
-		if name.Name == newName.Name {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "name already on the database"})
-			return
-		}
+	if _, ok := preloadTable.ByName(newName.Name); ok {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "name already on the database"})
+		return
 	}
 
-	fmt.Println("chegou aqui")
-
 	// Create name
 	err := newName.CreateName()
 	if err != nil {
@@ -46,15 +41,12 @@ func CreateName(c *gin.Context) {
 		return
 	}
 
-	// Clear cache
-	clearCache(c)
+	// Add the name to the cache
+	patchCache(c, []models.NameType{newName}, nil)
 
 	// Return successful response
 	c.JSON(http.StatusOK, gin.H{"Message": "Name created"})
 
-	// Clear the cache
-	clearCache(c)
-
 	return
 }
 
@@ -106,6 +98,26 @@ func GetName(c *gin.Context) {
 	return
 }
 
+// GetVariation reads every canonical name that lists the given name as a variation
+func GetVariation(c *gin.Context) {
+	// Get variation to be searched
+	param := c.Params.ByName("name")
+
+	// Check the cache
+	preloadTable := checkCache(c)
+
+	// Search for canonical names
+	names := preloadTable.ByVariation(strings.ToUpper(param))
+	if len(names) == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "variation not found"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, names)
+	return
+}
+
 //GetMetaphoneMatch reads a name by metaphone
 func GetMetaphoneMatch(c *gin.Context) {
 	// Get name to be searched
@@ -114,8 +126,32 @@ func GetMetaphoneMatch(c *gin.Context) {
 	// Check the cache
 	preloadTable := checkCache(c)
 
+	// Return the ranked candidates if the top parameter is passed by middlewares
+	if top := c.GetInt("top"); top != 0 {
+		candidates, err := models.GetSimilarCandidates(name, preloadTable, top, getMatchOptions(c).Algorithm)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "error finding candidates"})
+			return
+		}
+
+		c.JSON(http.StatusOK, candidates)
+		return
+	}
+
+	// Return the canonical entity with the trace of the pipeline if the explain parameter is passed by middlewares
+	if c.GetBool("explain") {
+		canonicalEntity, trace, err := models.GetSimilarMatchTrace(name, preloadTable, getMatchOptions(c))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "error finding canonical entity", "Trace": trace})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"Result": canonicalEntity, "Trace": trace})
+		return
+	}
+
 	// Search for similar names
-	canonicalEntity, err := models.GetSimilarMatch(name, preloadTable)
+	canonicalEntity, err := models.GetSimilarMatch(name, preloadTable, getMatchOptions(c))
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "error finding canonical entity"})
 		return
@@ -126,6 +162,33 @@ func GetMetaphoneMatch(c *gin.Context) {
 	return
 }
 
+// GetBatchMetaphoneMatch normalizes a batch of full names by metaphone
+func GetBatchMetaphoneMatch(c *gin.Context) {
+	// The names are passed by middlewares
+	namesValue, ok := c.Get("names")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting names from middlewares"})
+		return
+	}
+
+	// Parse namesValue into []string
+	names, ok := namesValue.([]string)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to parse names"})
+		return
+	}
+
+	// Check the cache
+	preloadTable := checkCache(c)
+
+	// Resolve every full name
+	results := models.ResolveFullNames(names, preloadTable, getMatchOptions(c))
+
+	// Return successful response
+	c.JSON(http.StatusOK, results)
+	return
+}
+
 // UpdateName updates name by id
 func UpdateName(c *gin.Context) {
 	// Convert id string into int
@@ -137,7 +200,7 @@ func UpdateName(c *gin.Context) {
 	}
 
 	// Get the name by id
-	name, db, err := models.GetNameById(id)
+	name, _, err := models.GetNameById(id)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error getting name by id"})
 		return
@@ -158,18 +221,18 @@ func UpdateName(c *gin.Context) {
 	}
 
 	// Update the name get by id with the updated struct
-	un, err := name.UpdateName(db, updateName)
+	un, err := name.UpdateName(updateName)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "error on updating item: " + err.Error()})
 		return
 	}
 
+	// Replace the name on the cache
+	patchCache(c, []models.NameType{un}, nil)
+
 	// Return the updated name
 	c.JSON(http.StatusOK, un)
 
-	// Clear the cache
-	clearCache(c)
-
 	return
 }
 
@@ -197,48 +260,84 @@ func DeleteName(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"Message": "id deleted"})
+	// Remove the name from the cache
+	patchCache(c, nil, []uint{name.ID})
 
-	// Clear the cache
-	clearCache(c)
+	c.JSON(http.StatusOK, gin.H{"Message": "id deleted"})
 
 	return
 }
 
-// checkCache retrieves the cached name types from the context if they exist, otherwise it retrieves them from the database
-func checkCache(c *gin.Context) []models.NameType {
-	// Initialize a variable for the cached name types
-	var preloadTable []models.NameType
+// checkCache retrieves the cached name index from the context if it exists, otherwise it builds it from the database
+func checkCache(c *gin.Context) *models.NameIndex {
+	// Initialize a variable for the cached name index
+	var preloadTable *models.NameIndex
 
-	// Get the cached name types from the context
+	// Get the cached name index from the context
 	cache, existKey := c.Get("nameTypes")
 	if existKey {
-		preloadTable = cache.([]models.NameType)
+		preloadTable = cache.(*models.NameIndex)
 	} else {
 		allNames, err := models.GetAllNames()
 		if err != nil {
-			// If there is an error retrieving the name types from the database, return nil
+			// If there is an error retrieving the name types from the database, return an empty index
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error on caching all name types"})
-			return nil
+			return models.NewNameIndex(nil)
 		}
-		// Set the retrieved name types in the cache
-		preloadTable = allNames
+		// Set the retrieved name index in the cache
+		preloadTable = models.NewNameIndex(allNames)
 		c.Set("nameTypes", preloadTable)
 	}
 
-	// Return the cached name types
+	// Return the cached name index
 	return preloadTable
 }
 
-// clearCache deletes the cached name types from the context if they exist
-func clearCache(c *gin.Context) {
-	// Get the cached name types from the context
-	cache, exist := c.Get("nameTypes")
-	// If the name types are found in the cache, delete them
-	if exist {
-		// Convert the cache to a sync.Map so that we can delete the cached name types
-		if cm, ok := cache.(sync.Map); ok {
-			cm.Delete("preloadTable")
-		}
+// getMatchOptions retrieves the match options passed by middlewares, otherwise it returns the default options
+func getMatchOptions(c *gin.Context) models.MatchOptions {
+	if opts, ok := c.Get("matchOptions"); ok {
+		return opts.(models.MatchOptions)
+	}
+	return models.DefaultMatchOptions()
+}
+
+// patchCache applies a committed write to the name cache passed by middlewares, without reloading the names
+func patchCache(c *gin.Context, upserts []models.NameType, deletes []uint) {
+	if cache, ok := getNameCache(c); ok {
+		cache.Apply(upserts, deletes)
+	}
+}
+
+// getNameCache retrieves the name cache passed by middlewares
+func getNameCache(c *gin.Context) (*models.NameCache, bool) {
+	value, exist := c.Get("nameCache")
+	if !exist {
+		return nil, false
+	}
+	cache, ok := value.(*models.NameCache)
+	return cache, ok
+}
+
+// ListNames reads a page of the names that match the listing options
+func ListNames(c *gin.Context) {
+	// The options are passed by middlewares
+	value, _ := c.Get("listOptions")
+	opts, ok := value.(models.ListOptions)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting list options from middlewares"})
+		return
 	}
+
+	page, err := models.ListNames(opts)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on listing names"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, page)
 }
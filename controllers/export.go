@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportNames streams the names that match the export options as a CSV, JSON or NDJSON file
+func ExportNames(c *gin.Context) {
+	// The options are passed by middlewares
+	value, _ := c.Get("exportOptions")
+	opts, ok := value.(database.ExportOptions)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting export options from middlewares"})
+		return
+	}
+
+	c.Header("Content-Type", opts.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="names.`+opts.Format+`"`)
+	c.Status(http.StatusOK)
+
+	_, err := database.ExportNames(models.DB, c.Writer, opts)
+	if err != nil {
+		// The status is sent with the first names, so a failure after them can only cut the file short
+		log.Printf("Error exporting names: %v", err)
+		if !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on exporting names"})
+			return
+		}
+		c.Abort()
+	}
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetGender reads the classification of a name with its confidence
+func GetGender(c *gin.Context) {
+	// Get name to be classified
+	name := c.Params.ByName("name")
+
+	// Check the cache
+	preloadTable := checkCache(c)
+
+	// Classify the name
+	result, err := models.GetGender(name, preloadTable, getMatchOptions(c))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "error finding classification"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, result)
+}
+
+// GetBatchGender reads the classification of a batch of names with their confidence
+func GetBatchGender(c *gin.Context) {
+	// The names are passed by middlewares
+	namesValue, ok := c.Get("names")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting names from middlewares"})
+		return
+	}
+
+	// Parse namesValue into []string
+	names, ok := namesValue.([]string)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to parse names"})
+		return
+	}
+
+	// Check the cache
+	preloadTable := checkCache(c)
+
+	// Classify every name
+	results := models.GetGenders(names, preloadTable, getMatchOptions(c))
+
+	// Return successful response
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz reports that the server is alive
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"Status": "ok"})
+}
+
+// Readyz reports whether the server can take traffic: the database must be reachable and the name cache loaded
+func Readyz(c *gin.Context) {
+	// Check the database
+	if err := models.PingDB(); err != nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"Status": "not ready", "error": "database unreachable"})
+		return
+	}
+
+	// Check the cache, without loading it
+	cache, ok := getNameCache(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting cache from middlewares"})
+		return
+	}
+	snapshot, ok := cache.Loaded()
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"Status": "not ready", "error": "name cache not loaded"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, gin.H{"Status": "ready", "Cache": snapshot})
+}
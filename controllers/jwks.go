@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSMaxAge is how long clients may cache the key set, in seconds
+const JWKSMaxAge = 300
+
+// JWKS returns the public keys verifying the JWT tokens, so other services verify them without a secret
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(JWKSMaxAge))
+	c.JSON(http.StatusOK, models.Keys.JWKS())
+}
@@ -0,0 +1,109 @@
+package controllers_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/controllers"
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// useKeys signs and verifies the tokens of the test with the KeySet of the given environment variables
+func useKeys(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range []string{"JWT_ALGORITHM", "SECRET", "JWT_PRIVATE_KEY_FILE", "JWT_PUBLIC_KEY_FILES"} {
+		t.Setenv(key, env[key])
+	}
+	ks, err := models.KeySetFromEnv()
+	if err != nil {
+		t.Fatalf("error loading signing keys: %v", err)
+	}
+	keys := models.Keys
+	models.Keys = ks
+	t.Cleanup(func() { models.Keys = keys })
+}
+
+// writePrivateKey writes the key to a PKCS #8 PEM file and returns its path
+func writePrivateKey(t *testing.T, key interface{}, name string) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("error encoding private key: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), name)
+	if err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("error writing key file: %v", err)
+	}
+	return file
+}
+
+// The kid of each key of the JWKS is its JWK thumbprint (RFC 7638)
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating Ed25519 key: %v", err)
+	}
+	useKeys(t, map[string]string{
+		"JWT_ALGORITHM":        models.AlgorithmRS256,
+		"JWT_PRIVATE_KEY_FILE": writePrivateKey(t, rsaKey, "rsa.pem"),
+		"JWT_PUBLIC_KEY_FILES": writePrivateKey(t, edKey, "ed25519.pem"),
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/.well-known/jwks.json", controllers.JWKS)
+	w := serve(r, http.MethodGet, "/.well-known/jwks.json", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if got, want := w.Header().Get("Cache-Control"), fmt.Sprintf("public, max-age=%d", controllers.JWKSMaxAge); got != want {
+		t.Errorf("Cache-Control = %q, want %q", got, want)
+	}
+
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err = json.Unmarshal(w.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("error decoding JWKS: %v", err)
+	}
+	if len(jwks.Keys) != 2 || jwks.Keys[0]["kty"] != "RSA" || jwks.Keys[1]["kty"] != "OKP" {
+		t.Fatalf("JWKS = %s, want the RSA signing key and the Ed25519 key", w.Body.String())
+	}
+
+	for _, k := range jwks.Keys {
+		// The thumbprint is the SHA-256 of the required members of the key, in lexicographic order
+		canonical := fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k["crv"], k["x"])
+		if k["kty"] == "RSA" {
+			canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k["e"], k["n"])
+		}
+		sum := sha256.Sum256([]byte(canonical))
+		if want := base64.RawURLEncoding.EncodeToString(sum[:]); k["kid"] != want {
+			t.Errorf("%s key has kid %s, want its thumbprint %s", k["kty"], k["kid"], want)
+		}
+		if k["use"] != "sig" {
+			t.Errorf("%s key has use %q, want sig", k["kty"], k["use"])
+		}
+	}
+
+	// The RSA modulus and exponent are the ones of the signing key
+	n, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0]["n"])
+	if string(n) != string(rsaKey.N.Bytes()) || jwks.Keys[0]["e"] != "AQAB" {
+		t.Errorf("RSA key n and e don't match the signing key")
+	}
+}
@@ -3,17 +3,21 @@ package controllers
 import (
 	"errors"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/Darklabel91/API_Names/models"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Login verifies email and password and sets a JWT token as a cookie for authentication
+const (
+	TokenCookie        = "token"
+	RefreshTokenCookie = "refresh_token"
+)
+
+// Login verifies email and password, starts a session and sets its JWT token and refresh token as cookies for authentication
 func Login(c *gin.Context) {
 	// Get email and password from request body
 	var body models.UserInputBody
@@ -36,38 +40,69 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateJWTToken(u.ID, 1*time.Hour*24)
+	// Start a session
+	sessionID, err := models.NewTokenID()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// Set token as a cookie
-	c.SetCookie("token", token, int(1*time.Hour.Seconds()), "/", "", false, true)
+	// Generate the tokens and set them as cookies
+	err = issueTokens(c, u, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
 
 	// Return success response
 	c.JSON(http.StatusOK, gin.H{"Message": "Login successful"})
 }
 
-//generateJWTToken generates a JWT token with a specified expiration time and user ID. It first sets the token expiration time based on the amountDays parameter passed into the function.
-func generateJWTToken(userID uint, amountDays time.Duration) (string, error) {
-	// Set token expiration time
-	expirationTime := time.Now().Add(amountDays * 24 * time.Hour)
+// issueTokens generates a JWT token and a refresh token of the session with the given id and sets them as cookies
+func issueTokens(c *gin.Context, u models.User, sessionID string) error {
+	jti, err := models.NewTokenID()
+	if err != nil {
+		return err
+	}
 
+	expiresAt := time.Now().Add(models.Auth.AccessTokenTTL)
+	token, err := generateJWTToken(u.ID, u.Role, jti, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	refreshToken, err := u.CreateRefreshToken(sessionID, jti, expiresAt, models.Auth.RefreshTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	setCookie(c, TokenCookie, token, models.Auth.CookieMaxAge)
+	setCookie(c, RefreshTokenCookie, refreshToken, models.Auth.RefreshTokenTTL)
+	return nil
+}
+
+// setCookie sets an HTTP-only cookie with the settings of models.Auth. A negative max age deletes the cookie.
+func setCookie(c *gin.Context, name, value string, maxAge time.Duration) {
+	c.SetSameSite(models.Auth.CookieSameSite)
+	c.SetCookie(name, value, int(maxAge.Seconds()), "/", models.Auth.CookieDomain, models.Auth.CookieSecure, true)
+}
+
+// generateJWTToken generates a JWT token with the given user ID, role, token ID and expiration time, issued by and
+// for models.Auth and signed by models.Keys. The token ID is set on the jti claim, so the token can be revoked.
+func generateJWTToken(userID uint, role, jti string, expiresAt time.Time) (string, error) {
 	// Create JWT claims
 	claims := jwt.MapClaims{
-		"exp": expirationTime.Unix(),
-		"iat": time.Now().Unix(),
-		"sub": strconv.Itoa(int(userID)),
+		"exp":  expiresAt.Unix(),
+		"iat":  time.Now().Unix(),
+		"sub":  strconv.Itoa(int(userID)),
+		"jti":  jti,
+		"iss":  models.Auth.Issuer,
+		"aud":  models.Auth.Audience,
+		"role": role,
 	}
 
-	// Create token using claims and signing method
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign token using secret key
-	secretKey := []byte(os.Getenv("SECRET"))
-	signedToken, err := token.SignedString(secretKey)
+	// Sign token using the signing key
+	signedToken, err := models.Keys.Sign(claims)
 	if err != nil {
 		return "", errors.New("failed to sign token")
 	}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserRoles reads the role of every user
+func GetUserRoles(c *gin.Context) {
+	roles, err := models.GetUserRoles()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting user roles"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, roles)
+}
+
+// GrantRole sets the role of the user with the given id
+func GrantRole(c *gin.Context) {
+	// The role is passed by middlewares
+	value, _ := c.Get("roleInput")
+	input, ok := value.(models.RoleInput)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting role from middlewares"})
+		return
+	}
+
+	setRole(c, input.Role)
+}
+
+// RevokeRole sets the role of the user with the given id back to models.DefaultRole
+func RevokeRole(c *gin.Context) {
+	setRole(c, models.DefaultRole)
+}
+
+// setRole saves the role of the user with the id of the URL and revokes the sessions of the user, so no token keeps
+// the previous role. The root user stays an admin, so the service always has one.
+func setRole(c *gin.Context, role string) {
+	// The id is validated by middlewares
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id parameter, it must be a valid integer"})
+		return
+	}
+	if id == models.RootUserID {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "the role of the root user can't be changed"})
+		return
+	}
+
+	u, err := models.GetUserByID(uint(id))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	err = u.SetRole(role)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on updating role"})
+		return
+	}
+
+	// Revoke the tokens carrying the previous role, the user logs in again with the new one
+	_, err = u.RevokeSessions()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on revoking sessions"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, gin.H{"Message": "Role updated", "User": models.UserRole{ID: u.ID, Email: u.Email, Role: role}})
+}
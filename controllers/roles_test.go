@@ -0,0 +1,100 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/controllers"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/middlewares"
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// createUser creates a user with the given role, the first one created is the root user
+func createUser(t *testing.T, email, role string) models.User {
+	t.Helper()
+	u := models.User{Email: email, Password: "hash", Role: role}
+	if _, err := u.CreateUser(); err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	return u
+}
+
+// roleRoutes returns a router with the role routes of the admin group, without their authentication
+func roleRoutes() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/admin/users/:id/role", middlewares.ValidateID(), middlewares.ValidateRoleJSON(), controllers.GrantRole)
+	r.DELETE("/admin/users/:id/role", middlewares.ValidateID(), controllers.RevokeRole)
+	return r
+}
+
+func serve(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGrantAndRevokeRole(t *testing.T) {
+	dbtest.Open(t)
+	root := createUser(t, "root@root.com", models.RoleAdmin)
+	if root.ID != models.RootUserID {
+		t.Fatalf("root user id = %d, want %d", root.ID, models.RootUserID)
+	}
+	user := createUser(t, "user@test.com", "")
+	r := roleRoutes()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		role   string
+	}{
+		{"grant editor", http.MethodPut, "/admin/users/2/role", `{"Role":"Editor"}`, http.StatusOK, models.RoleEditor},
+		{"grant admin", http.MethodPut, "/admin/users/2/role", `{"Role":"admin"}`, http.StatusOK, models.RoleAdmin},
+		{"unknown role", http.MethodPut, "/admin/users/2/role", `{"Role":"root"}`, http.StatusBadRequest, models.RoleAdmin},
+		{"empty role", http.MethodPut, "/admin/users/2/role", `{}`, http.StatusBadRequest, models.RoleAdmin},
+		{"revoke", http.MethodDelete, "/admin/users/2/role", ``, http.StatusOK, models.RoleReader},
+		{"unknown user", http.MethodPut, "/admin/users/99/role", `{"Role":"editor"}`, http.StatusNotFound, models.RoleReader},
+		{"invalid id", http.MethodPut, "/admin/users/two/role", `{"Role":"editor"}`, http.StatusBadRequest, models.RoleReader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.path, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			u, err := models.GetUserByID(user.ID)
+			if err != nil || u.Role != tt.role {
+				t.Fatalf("user role = %q, %v, want %q", u.Role, err, tt.role)
+			}
+		})
+	}
+}
+
+// The root user can't be demoted, so the service always has an admin
+func TestRootRoleCantChange(t *testing.T) {
+	dbtest.Open(t)
+	createUser(t, "root@root.com", models.RoleAdmin)
+	r := roleRoutes()
+
+	for _, w := range []*httptest.ResponseRecorder{
+		serve(r, http.MethodPut, "/admin/users/1/role", `{"Role":"reader"}`),
+		serve(r, http.MethodDelete, "/admin/users/1/role", ``),
+	} {
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	}
+	root, err := models.GetUserByID(models.RootUserID)
+	if err != nil || root.Role != models.RoleAdmin {
+		t.Fatalf("root role = %q, %v, want admin", root.Role, err)
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshToken rotates the refresh token of a session: it spends the refresh token of the cookie or body and sets a
+// new JWT token and refresh token as cookies. A refresh token used twice revokes its session.
+func RefreshToken(c *gin.Context) {
+	token := refreshTokenOf(c)
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "refresh token not found"})
+		return
+	}
+
+	rt, u, err := models.SpendRefreshToken(token)
+	if errors.Is(err, models.ErrRefreshTokenReused) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "refresh token already used, the session was revoked"})
+		return
+	}
+	if errors.Is(err, models.ErrInvalidRefreshToken) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid, expired or revoked refresh token"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on refreshing token"})
+		return
+	}
+
+	// Generate the next tokens of the session, with the current role of the user
+	err = issueTokens(c, u, rt.SessionID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{"Message": "Token refreshed"})
+}
+
+// Logout revokes the session of the refresh token of the cookie or body, with its JWT tokens, and clears the cookies
+func Logout(c *gin.Context) {
+	token := refreshTokenOf(c)
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "refresh token not found"})
+		return
+	}
+
+	// Unknown tokens have no session to revoke
+	rt, err := models.GetRefreshToken(token)
+	if err != nil && !errors.Is(err, models.ErrInvalidRefreshToken) {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on logging out"})
+		return
+	}
+	if err == nil {
+		err = models.RevokeSession(rt.SessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on logging out"})
+			return
+		}
+	}
+
+	setCookie(c, TokenCookie, "", -time.Second)
+	setCookie(c, RefreshTokenCookie, "", -time.Second)
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{"Message": "Logout successful"})
+}
+
+// RevokeUserSessions revokes every session of the user with the given id, with their JWT tokens
+func RevokeUserSessions(c *gin.Context) {
+	// The id is validated by middlewares
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id parameter, it must be a valid integer"})
+		return
+	}
+
+	u, err := models.GetUserByID(uint(id))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	n, err := u.RevokeSessions()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on revoking sessions"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, gin.H{"Message": "Sessions revoked", "Sessions": n})
+}
+
+// refreshTokenOf returns the refresh token of the cookie, or of the body for clients without cookies
+func refreshTokenOf(c *gin.Context) string {
+	if token, err := c.Cookie(RefreshTokenCookie); err == nil && token != "" {
+		return token
+	}
+
+	var input models.RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return ""
+	}
+	return input.RefreshToken
+}
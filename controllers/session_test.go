@@ -0,0 +1,59 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Darklabel91/API_Names/controllers"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+// refreshCookie returns the refresh token set as a cookie by the response
+func refreshCookie(t *testing.T, header http.Header) string {
+	t.Helper()
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		if cookie.Name == controllers.RefreshTokenCookie {
+			return cookie.Value
+		}
+	}
+	t.Fatalf("response without the %s cookie", controllers.RefreshTokenCookie)
+	return ""
+}
+
+// A role change revokes the sessions of the user, so a refresh token issued with the old role can't be used
+func TestRefreshTokenAfterRoleChange(t *testing.T) {
+	dbtest.Open(t)
+	useKeys(t, map[string]string{"SECRET": "test-secret"})
+	createUser(t, "root@root.com", models.RoleAdmin)
+	user := createUser(t, "user@test.com", models.RoleReader)
+	r := roleRoutes()
+	r.POST("/token/refresh", controllers.RefreshToken)
+
+	sessionID, err := models.NewTokenID()
+	if err != nil {
+		t.Fatalf("error generating session id: %v", err)
+	}
+	token, err := user.CreateRefreshToken(sessionID, "access", time.Now().Add(time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("error creating refresh token: %v", err)
+	}
+
+	// The token is rotated while the role is unchanged
+	w := serve(r, http.MethodPost, "/token/refresh", `{"RefreshToken":"`+token+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	token = refreshCookie(t, w.Header())
+
+	w = serve(r, http.MethodPut, "/admin/users/2/role", `{"Role":"editor"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("grant status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	w = serve(r, http.MethodPost, "/token/refresh", `{"RefreshToken":"`+token+`"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after the role change status = %d, want %d: %s", w.Code, http.StatusUnauthorized, w.Body.String())
+	}
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserMatchOptions returns the default match options of the authenticated user
+func GetUserMatchOptions(c *gin.Context) {
+	// Get the authenticated user
+	u, err := models.GetUserByID(c.GetUint("userID"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, u.MatchOptions())
+}
+
+// UpdateUserMatchOptions updates the default match options of the authenticated user
+func UpdateUserMatchOptions(c *gin.Context) {
+	// Get the options from request body
+	var body models.MatchOptionsInput
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON request"})
+		return
+	}
+
+	// Get the authenticated user
+	u, err := models.GetUserByID(c.GetUint("userID"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	// Validate and save the options
+	opts, err := u.UpdateMatchOptions(body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "error on updating match options: " + err.Error()})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, opts)
+}
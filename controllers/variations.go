@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// AddVariations adds variations to a name by id
+func AddVariations(c *gin.Context) {
+	// Get the name and the variations
+	name, input, ok := getNameAndVariations(c)
+	if !ok {
+		return
+	}
+
+	// Add the variations
+	n, err := name.AddVariations(input.Variations, input.Source)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on adding variations: " + err.Error()})
+		return
+	}
+
+	// Replace the name on the cache
+	patchCache(c, []models.NameType{n}, nil)
+
+	// Return the updated name
+	c.JSON(http.StatusOK, n)
+}
+
+// DeleteVariations deletes variations of a name by id
+func DeleteVariations(c *gin.Context) {
+	// Get the name and the variations
+	name, input, ok := getNameAndVariations(c)
+	if !ok {
+		return
+	}
+
+	// Delete the variations
+	n, err := name.DeleteVariations(input.Variations)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "error on deleting variations: " + err.Error()})
+		return
+	}
+
+	// Replace the name on the cache
+	patchCache(c, []models.NameType{n}, nil)
+
+	// Return the updated name
+	c.JSON(http.StatusOK, n)
+}
+
+// getNameAndVariations gets the name by the id param and the variations passed by middlewares.
+// It aborts the request and returns false if any of them is missing.
+func getNameAndVariations(c *gin.Context) (*models.NameType, models.VariationsInput, bool) {
+	// Convert id string into int
+	id, err := strconv.Atoi(c.Params.ByName("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on parsing id"})
+		return nil, models.VariationsInput{}, false
+	}
+
+	// The variations are passed by middlewares
+	inputValue, ok := c.Get("variations")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on getting variations from middlewares"})
+		return nil, models.VariationsInput{}, false
+	}
+	input, ok := inputValue.(models.VariationsInput)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to parse variations"})
+		return nil, models.VariationsInput{}, false
+	}
+
+	// Get the name by id
+	name, _, err := models.GetNameById(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "name id not found"})
+		return nil, models.VariationsInput{}, false
+	}
+
+	return name, input, true
+}
@@ -0,0 +1,131 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/controllers"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/middlewares"
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// variationRoutes returns a router with the variation and delete routes of the editors, patching the given cache
+func variationRoutes(cache *models.NameCache) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("nameCache", cache) })
+	r.DELETE("/:id", middlewares.ValidateID(), controllers.DeleteName)
+	r.POST("/name/:id/variations", middlewares.ValidateID(), middlewares.ValidateVariationsJSON(), controllers.AddVariations)
+	r.DELETE("/name/:id/variations", middlewares.ValidateID(), middlewares.ValidateVariationsJSON(), controllers.DeleteVariations)
+	return r
+}
+
+// cachedVariations returns the variations of the name with the given id on the cache, and if the name is cached
+func cachedVariations(t *testing.T, cache *models.NameCache, id uint) ([]string, bool) {
+	t.Helper()
+	snapshot, err := cache.Snapshot()
+	if err != nil {
+		t.Fatalf("error getting snapshot: %v", err)
+	}
+	n, ok := snapshot.Index.ByID(id)
+	return models.SplitVariations(n.NameVariations), ok
+}
+
+func equalVariations(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestVariationsPatchCache(t *testing.T) {
+	dbtest.Open(t)
+	n := models.NameType{Name: "MARIA", Classification: models.ClassificationFemale, NameVariations: "|MARYA|"}
+	if err := n.CreateName(); err != nil {
+		t.Fatalf("error creating name: %v", err)
+	}
+	cache := models.NewNameCache(models.DBNameSource{})
+	r := variationRoutes(cache)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		want   []string
+	}{
+		{"add", http.MethodPost, `{"Variations":["mariah", "Marya"]}`, http.StatusOK, []string{"MARIAH", "MARYA"}},
+		{"delete", http.MethodDelete, `{"Variations":["MARYA"]}`, http.StatusOK, []string{"MARIAH"}},
+		{"delete missing", http.MethodDelete, `{"Variations":["MARYA"]}`, http.StatusNotFound, []string{"MARIAH"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, "/name/1/variations", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+
+			// The cached entry and the table have the same variations
+			cached, ok := cachedVariations(t, cache, n.ID)
+			if !ok || !equalVariations(cached, tt.want) {
+				t.Fatalf("cached variations = %v, want %v", cached, tt.want)
+			}
+			rows, err := models.GetVariations(n.ID)
+			if err != nil {
+				t.Fatalf("error getting variations: %v", err)
+			}
+			var stored []string
+			for _, v := range rows {
+				stored = append(stored, v.Variation)
+			}
+			if !equalVariations(stored, tt.want) {
+				t.Fatalf("stored variations = %v, want %v", stored, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteNameRemovesVariations(t *testing.T) {
+	db := dbtest.Open(t)
+	n := models.NameType{Name: "MARIA", Classification: models.ClassificationFemale, NameVariations: "|MARYA|MARIAH|"}
+	other := models.NameType{Name: "ANA", Classification: models.ClassificationFemale, NameVariations: "|ANNA|"}
+	for _, name := range []*models.NameType{&n, &other} {
+		if err := name.CreateName(); err != nil {
+			t.Fatalf("error creating name: %v", err)
+		}
+	}
+	cache := models.NewNameCache(models.DBNameSource{})
+	if _, err := cache.Snapshot(); err != nil {
+		t.Fatalf("error loading cache: %v", err)
+	}
+
+	w := serve(variationRoutes(cache), http.MethodDelete, "/1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	// The variations of the name are gone, the ones of other names are kept
+	var count int64
+	if err := db.Model(&models.NameVariation{}).Where("name_type_id = ?", n.ID).Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("variations of the deleted name = %d, %v, want 0", count, err)
+	}
+	if rows, err := models.GetVariations(other.ID); err != nil || len(rows) != 1 {
+		t.Fatalf("variations of the other name = %v, %v, want 1", rows, err)
+	}
+
+	// The name and its variations are gone from the cache
+	if _, ok := cachedVariations(t, cache, n.ID); ok {
+		t.Fatal("deleted name still cached")
+	}
+	snapshot, _ := cache.Snapshot()
+	if names := snapshot.Index.ByVariation("MARYA"); len(names) != 0 {
+		t.Fatalf("ByVariation(MARYA) = %v, want no names", names)
+	}
+}
@@ -1,55 +1,112 @@
 package database
 
 import (
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"github.com/Darklabel91/API_Names/models"
-	"github.com/Darklabel91/metaphone-br"
+	"github.com/glebarez/sqlite"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"io"
+	"io/fs"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// ConnectDB opens a connection to the database and migrates tables using ORM
-func ConnectDB() (*gorm.DB, error) {
-	// Load environment variables from .env file
+// Database drivers selected by the DB_DRIVER environment variable
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Config is the configuration of the database connection, read from the environment by ConfigFromEnv
+type Config struct {
+	Driver   string
+	Username string
+	Password string
+	// Name is the name of the database, or the path of the database file on SQLite
+	Name    string
+	Host    string
+	Port    string
+	SSLMode string
+}
+
+// ConfigFromEnv reads the configuration of the database connection from the environment. The driver defaults to MySQL.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Driver:   strings.ToLower(os.Getenv("DB_DRIVER")),
+		Username: os.Getenv("DB_USERNAME"),
+		Password: os.Getenv("DB_PASSWORD"),
+		Name:     os.Getenv("DB_NAME"),
+		Host:     os.Getenv("DB_HOST"),
+		Port:     os.Getenv("DB_PORT"),
+		SSLMode:  os.Getenv("DB_SSLMODE"),
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = DriverMySQL
+	}
+	if cfg.SSLMode == "" {
+		cfg.SSLMode = "disable"
+	}
+	return cfg
+}
+
+// OpenDB opens a connection to the database, creating it if it doesn't exist. The schema is not checked, see ConnectDB.
+func OpenDB() (*gorm.DB, error) {
+	// Load environment variables from .env file, if there is one
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("error loading .env file: %v", err)
 	}
-
-	// Get environment variables
-	dbUsername := os.Getenv("DB_USERNAME")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
+	cfg := ConfigFromEnv()
 
 	// Create database
-	err = createDatabase(dbHost, dbUsername, dbPassword, dbName)
+	err = createDatabase(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("error creating database: %v", err)
 	}
 
 	// Connect to the database
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=Local", dbUsername, dbPassword, dbHost, dbPort, dbName)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	dialector, err := cfg.dialector()
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("error openning db connection: %v", err)
 	}
 
+	return db, nil
+}
+
+// ConnectDB opens a connection to the database, checks that every migration is applied and seeds the NameType table.
+// The pending migrations are applied first when DB_AUTO_MIGRATE is true, otherwise they fail with ErrSchemaBehind.
+func ConnectDB() (*gorm.DB, error) {
+	db, err := OpenDB()
+	if err != nil {
+		return nil, err
+	}
+
 	// Migrate tables
-	err = db.AutoMigrate(&models.NameType{}, &models.User{}, &models.Log{})
+	if autoMigrate, _ := strconv.ParseBool(os.Getenv("DB_AUTO_MIGRATE")); autoMigrate {
+		_, err = MigrateUp(db)
+		if err != nil {
+			return nil, fmt.Errorf("error migrating database: %v", err)
+		}
+	}
+	err = checkSchema(db)
 	if err != nil {
-		return nil, fmt.Errorf("error automigrating tables: %v", err)
+		return nil, fmt.Errorf("%w, run the migrate up command", err)
 	}
 
 	// Upload CSV data to NameType table
-	err = uploadCSVNameTypes(db)
+	err = uploadCSVNameTypes(db, SeedFile)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting db to upload csv: %v", err)
 	}
@@ -57,26 +114,69 @@ func ConnectDB() (*gorm.DB, error) {
 	return db, nil
 }
 
-// createDatabase runs the create database script
-func createDatabase(host, username, password, dbName string) error {
-	// Set up the MySQL DSN string
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/?charset=utf8mb4&parseTime=True&loc=Local", username, password, host)
+// dialector returns the gorm dialector of the driver connected to the database of the configuration
+func (cfg Config) dialector() (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=true&loc=Local", cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		return postgres.Open(cfg.postgresDSN(cfg.Name)), nil
+	case DriverSQLite:
+		// Wait for the locks of concurrent writers instead of failing and enforce the foreign keys as the other drivers
+		return sqlite.Open(cfg.Name + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"), nil
+	default:
+		return nil, fmt.Errorf("invalid DB_DRIVER %q: driver must be mysql, postgres or sqlite", cfg.Driver)
+	}
+}
+
+// postgresDSN returns the PostgreSQL DSN of the given database on the server of the configuration
+func (cfg Config) postgresDSN(dbName string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s", cfg.Host, cfg.Port, cfg.Username, cfg.Password, dbName, cfg.SSLMode)
+}
+
+// createDatabase creates the database of the configuration if it doesn't exist. SQLite creates the database file when
+// it is opened.
+func createDatabase(cfg Config) error {
+	var dialector gorm.Dialector
+	var exists, create string
+	switch cfg.Driver {
+	case DriverMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=utf8mb4&parseTime=True&loc=Local", cfg.Username, cfg.Password, cfg.Host, cfg.Port)
+		dialector = mysql.Open(dsn)
+		exists = "SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?"
+		create = "CREATE DATABASE `%s`"
+	case DriverPostgres:
+		dialector = postgres.Open(cfg.postgresDSN("postgres"))
+		exists = "SELECT COUNT(*) FROM pg_database WHERE datname = ?"
+		create = `CREATE DATABASE "%s"`
+	default:
+		return nil
+	}
 
-	// Open a connection to the MySQL server
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// Open a connection to the server
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", cfg.Driver, err)
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to connect to MySQL: %v", err)
+		return fmt.Errorf("failed to connect to %s: %v", cfg.Driver, err)
 	}
+	defer sqlDB.Close()
 
 	// Check if the database already exists
 	var result int64
-	db.Raw("SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", dbName).Scan(&result)
+	err = db.Raw(exists, cfg.Name).Scan(&result).Error
+	if err != nil {
+		return fmt.Errorf("failed to check database: %v", err)
+	}
 	if result > 0 {
 		return nil
 	}
 
 	// Create the database
-	err = db.Exec(fmt.Sprintf("CREATE DATABASE %s", dbName)).Error
+	err = db.Exec(fmt.Sprintf(create, cfg.Name)).Error
 	if err != nil {
 		return fmt.Errorf("failed to create database: %v", err)
 	}
@@ -85,51 +185,83 @@ func createDatabase(host, username, password, dbName string) error {
 	return nil
 }
 
-// uploadCSVNameTypes the specified CSV file to the database as NameType objects.
-func uploadCSVNameTypes(db *gorm.DB) error {
-	var name models.NameType
-	db.Raw("select * from name_types where id = 1").Find(&name)
+// SeedFile is the CSV file imported into the NameType table on the first run
+const SeedFile = "database/name_types .csv"
 
-	if name.ID == 0 {
-		start := time.Now()
-		log.Println("-	Upload data start")
+// uploadCSVNameTypes imports the seed CSV file into the NameType table with ImportNames. Every batch of rows is
+// imported in its own transaction and rows whose Name is already on the table are skipped, so an interrupted import
+// resumes where it stopped on the next run. Once the table has a row for every row of the file the seed is recorded
+// and it doesn't run again.
+func uploadCSVNameTypes(db *gorm.DB, seedFile string) error {
+	var seed models.Seed
+	err := db.Where("file = ?", seedFile).Limit(1).Find(&seed).Error
+	if err != nil {
+		return fmt.Errorf("error checking seed: %v", err)
+	}
+	if seed.ID != 0 {
+		return nil
+	}
 
-		filePath := "database/name_types .csv"
-		file, err := os.Open(filePath)
-		if err != nil {
-			return fmt.Errorf("error opening file:: %v", err)
-		}
-		defer file.Close()
-
-		reader := csv.NewReader(file)
-		var rows [][]string
-		for {
-			row, err := reader.Read()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return fmt.Errorf("error reading file:: %v", err)
-			}
-			rows = append(rows, row)
-		}
+	start := time.Now()
+	log.Println("-	Upload data start")
 
-		for i, row := range rows {
-			if i != 0 {
-				nameType := models.NameType{
-					Name:           row[0],
-					Classification: row[1],
-					Metaphone:      metaphone.Pack(row[0]),
-					NameVariations: row[3],
-				}
-				if err = db.Create(&nameType).Error; err != nil {
-					return fmt.Errorf("error creating NameType:: %v", err)
-				}
-			}
-		}
+	file, err := os.Open(seedFile)
+	if err != nil {
+		return fmt.Errorf("error opening file:: %v", err)
+	}
+	defer file.Close()
 
-		log.Println("-	Upload data finished", time.Since(start).String())
-		return nil
+	report, err := ImportNames(db, &progressReader{Reader: file, total: fileSize(file)}, ImportOptions{Format: FormatCSV, Mode: ImportSkip, Source: models.SourceCSV})
+	if err != nil {
+		return fmt.Errorf("error importing file: %v", err)
+	}
+	if report.Failed != 0 {
+		e := report.Errors[0]
+		return fmt.Errorf("error parsing line %d: %v (%d rows failed)", e.Line, e.Error, report.Failed)
+	}
+
+	// Check that the table has a row for every row of the file before recording the seed
+	var count int64
+	err = db.Unscoped().Model(&models.NameType{}).Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("error counting names: %v", err)
+	}
+	if int(count) != report.Rows {
+		return fmt.Errorf("error verifying upload: %d names on the table, expected %d rows of %s", count, report.Rows, seedFile)
+	}
+	err = db.Create(&models.Seed{File: seedFile, Rows: report.Rows}).Error
+	if err != nil {
+		return fmt.Errorf("error recording seed: %v", err)
 	}
+
+	log.Printf("-	Upload data finished %s: %d rows, %d inserted, %d already on the table", time.Since(start).String(), report.Rows, report.Inserted, report.Skipped)
 	return nil
 }
+
+// progressReader logs how much of a file was read every 10%
+type progressReader struct {
+	io.Reader
+	total, read int64
+	logged      int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read += int64(n)
+	if r.total > 0 {
+		if percent := r.read * 100 / r.total / 10 * 10; percent > r.logged && percent < 100 {
+			r.logged = percent
+			log.Printf("-	Upload data progress: %d%%", percent)
+		}
+	}
+	return n, err
+}
+
+// fileSize returns the size of the file, 0 if it is unknown
+func fileSize(file *os.File) int64 {
+	info, err := file.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,71 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		driver  string
+		sslMode string
+	}{
+		{"defaults", map[string]string{}, database.DriverMySQL, "disable"},
+		{"postgres", map[string]string{"DB_DRIVER": "Postgres", "DB_SSLMODE": "require"}, database.DriverPostgres, "require"},
+		{"sqlite", map[string]string{"DB_DRIVER": "SQLITE"}, database.DriverSQLite, "disable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DB_DRIVER", "DB_SSLMODE"} {
+				t.Setenv(key, tt.env[key])
+			}
+			cfg := database.ConfigFromEnv()
+			if cfg.Driver != tt.driver || cfg.SSLMode != tt.sslMode {
+				t.Fatalf("ConfigFromEnv() = %+v, want driver %s and sslmode %s", cfg, tt.driver, tt.sslMode)
+			}
+		})
+	}
+}
+
+func TestOpenDBInvalidDriver(t *testing.T) {
+	t.Setenv("DB_DRIVER", "oracle")
+	if _, err := database.OpenDB(); err == nil {
+		t.Fatal("OpenDB() with an invalid driver succeeded, want an error")
+	}
+}
+
+func TestOpenDBSQLite(t *testing.T) {
+	db := dbtest.Open(t)
+
+	// The pragmas of the DSN are applied
+	pragmas := map[string]string{"journal_mode": "wal", "foreign_keys": "1", "busy_timeout": "5000"}
+	for pragma, want := range pragmas {
+		var got string
+		if err := db.Raw("PRAGMA " + pragma).Scan(&got).Error; err != nil {
+			t.Fatalf("error reading pragma %s: %v", pragma, err)
+		}
+		if got != want {
+			t.Errorf("PRAGMA %s = %s, want %s", pragma, got, want)
+		}
+	}
+
+	// The models write to the test database
+	n := models.NameType{Name: "MARIA", Classification: models.ClassificationFemale, NameVariations: "|MARIA|MARYA|"}
+	if err := n.CreateName(); err != nil {
+		t.Fatalf("error creating name: %v", err)
+	}
+	got, err := models.GetNameByName("MARIA")
+	if err != nil || got.ID != n.ID {
+		t.Fatalf("GetNameByName(MARIA) = %+v, %v, want the created name", got, err)
+	}
+	var variations int64
+	db.Model(&models.NameVariation{}).Where("name_type_id = ?", n.ID).Count(&variations)
+	if variations != 2 {
+		t.Fatalf("created name has %d variations, want 2", variations)
+	}
+}
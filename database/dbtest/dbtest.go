@@ -0,0 +1,36 @@
+// Package dbtest opens the databases of the tests: a new SQLite file per test, so the tests need no database server
+// and don't share rows.
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/models"
+	"gorm.io/gorm"
+)
+
+// Open returns a new SQLite database with every migration applied and points models.DB to it. The database is closed
+// when the test ends.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	t.Setenv("DB_DRIVER", database.DriverSQLite)
+	t.Setenv("DB_NAME", filepath.Join(t.TempDir(), "names.db"))
+
+	db, err := database.OpenDB()
+	if err != nil {
+		t.Fatalf("error opening test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err = database.MigrateUp(db); err != nil {
+		t.Fatalf("error migrating test database: %v", err)
+	}
+
+	models.DB = db
+	return db
+}
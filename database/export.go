@@ -0,0 +1,228 @@
+package database
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Darklabel91/API_Names/models"
+	"gorm.io/gorm"
+)
+
+// FormatJSON is the format of an export as a single JSON array
+const FormatJSON = "json"
+
+// ExportBatchSize is the number of rows read from the table at a time by an export
+const ExportBatchSize = 1000
+
+// ExportHeader is the header of an exported CSV file. The first columns are the ones of the seed file, so an export can
+// be seeded or imported into another environment.
+var ExportHeader = []string{"name", "classification", "parse", "name_variations", "male_weight", "female_weight"}
+
+// ExportOptions controls which names are exported and how
+type ExportOptions struct {
+	Format string
+	// Classification exports only the names with the given classification, all of them when empty
+	Classification string
+	// MetaphonePrefix exports only the names whose metaphone starts with the given prefix, all of them when empty
+	MetaphonePrefix string
+	// UpdatedSince exports only the names updated at or after the given time, all of them when nil
+	UpdatedSince *time.Time
+}
+
+// Validate checks the format of the options
+func (o ExportOptions) Validate() error {
+	if o.Format != FormatCSV && o.Format != FormatJSON && o.Format != FormatNDJSON {
+		return fmt.Errorf("invalid format %q: %w", o.Format, errors.New("format must be csv, json or ndjson"))
+	}
+	return nil
+}
+
+// ContentType returns the content type of the format of the options
+func (o ExportOptions) ContentType() string {
+	switch o.Format {
+	case FormatJSON:
+		return "application/json"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "text/csv"
+	}
+}
+
+// ExportNames writes the non-deleted names of the NameType table that match the options to w, ordered by id. The
+// names are read and written in batches, so the table is never loaded in memory. It returns the number of names
+// written.
+func ExportNames(db *gorm.DB, w io.Writer, opts ExportOptions) (int, error) {
+	if err := opts.Validate(); err != nil {
+		return 0, err
+	}
+
+	var writer exportWriter
+	switch opts.Format {
+	case FormatCSV:
+		writer = &csvExportWriter{w: csv.NewWriter(w)}
+	case FormatJSON:
+		writer = &jsonExportWriter{w: w}
+	case FormatNDJSON:
+		writer = &ndjsonExportWriter{w: w}
+	}
+
+	if err := writer.begin(); err != nil {
+		return 0, fmt.Errorf("error writing export: %w", err)
+	}
+
+	written := 0
+	var nameTypes []models.NameType
+	err := exportQuery(db, opts).FindInBatches(&nameTypes, ExportBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, n := range nameTypes {
+			if err := writer.write(n); err != nil {
+				return fmt.Errorf("error writing name %q: %w", n.Name, err)
+			}
+			written++
+		}
+		return writer.flush()
+	}).Error
+	if err != nil {
+		return written, fmt.Errorf("error exporting names: %w", err)
+	}
+
+	if err = writer.end(); err != nil {
+		return written, fmt.Errorf("error writing export: %w", err)
+	}
+	return written, nil
+}
+
+// exportQuery returns the query of the names that match the options
+func exportQuery(db *gorm.DB, opts ExportOptions) *gorm.DB {
+	query := db.Model(&models.NameType{}).Order("id")
+	if opts.Classification != "" {
+		query = query.Where("classification = ?", opts.Classification)
+	}
+	if opts.MetaphonePrefix != "" {
+		query = query.Where("metaphone LIKE ?", opts.MetaphonePrefix+"%")
+	}
+	if opts.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", *opts.UpdatedSince)
+	}
+	return query
+}
+
+// exportWriter writes the names of an export in a format
+type exportWriter interface {
+	begin() error
+	write(n models.NameType) error
+	// flush writes the buffered names, it is called after every batch
+	flush() error
+	end() error
+}
+
+// csvExportWriter writes the names as the rows of a CSV file with the ExportHeader
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) begin() error {
+	return e.w.Write(ExportHeader)
+}
+
+func (e *csvExportWriter) write(n models.NameType) error {
+	return e.w.Write([]string{
+		n.Name,
+		n.Classification,
+		n.Name + " (" + n.Metaphone + ")",
+		n.NameVariations,
+		formatWeight(n.MaleWeight),
+		formatWeight(n.FemaleWeight),
+	})
+}
+
+func (e *csvExportWriter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) end() error {
+	return e.flush()
+}
+
+// jsonExportWriter writes the names as the elements of a single JSON array
+type jsonExportWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (e *jsonExportWriter) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExportWriter) write(n models.NameType) error {
+	b, err := json.Marshal(rowOf(n))
+	if err != nil {
+		return err
+	}
+	separator := "\n"
+	if e.written {
+		separator = ",\n"
+	}
+	e.written = true
+	if _, err = io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonExportWriter) flush() error {
+	return nil
+}
+
+func (e *jsonExportWriter) end() error {
+	_, err := io.WriteString(e.w, "\n]\n")
+	return err
+}
+
+// ndjsonExportWriter writes the names as the lines of a NDJSON file
+type ndjsonExportWriter struct {
+	w io.Writer
+}
+
+func (e *ndjsonExportWriter) begin() error {
+	return nil
+}
+
+func (e *ndjsonExportWriter) write(n models.NameType) error {
+	return json.NewEncoder(e.w).Encode(rowOf(n))
+}
+
+func (e *ndjsonExportWriter) flush() error {
+	return nil
+}
+
+func (e *ndjsonExportWriter) end() error {
+	return nil
+}
+
+// rowOf returns the exported fields of the name
+func rowOf(n models.NameType) nameRow {
+	return nameRow{
+		Name:           n.Name,
+		Classification: n.Classification,
+		NameVariations: n.NameVariations,
+		MaleWeight:     n.MaleWeight,
+		FemaleWeight:   n.FemaleWeight,
+	}
+}
+
+// formatWeight formats a frequency weight of a CSV row, empty when it is not set
+func formatWeight(weight *float32) string {
+	if weight == nil {
+		return ""
+	}
+	return strconv.FormatFloat(float64(*weight), 'f', -1, 32)
+}
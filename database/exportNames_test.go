@@ -0,0 +1,126 @@
+package database_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+	"gorm.io/gorm"
+)
+
+// exportedName is the part of a name that an export carries to another environment
+type exportedName struct {
+	Name, Classification, NameVariations string
+	Metaphone, Soundex, DoubleMetaphone  string
+	MaleWeight, FemaleWeight             *float32
+}
+
+// exportedNames returns the non-deleted names of the table, ordered by name
+func exportedNames(t *testing.T, db *gorm.DB) []exportedName {
+	t.Helper()
+	var names []models.NameType
+	if err := db.Order("name").Find(&names).Error; err != nil {
+		t.Fatalf("error getting names: %v", err)
+	}
+	exported := make([]exportedName, 0, len(names))
+	for _, n := range names {
+		exported = append(exported, exportedName{
+			Name: n.Name, Classification: n.Classification, NameVariations: n.NameVariations,
+			Metaphone: n.Metaphone, Soundex: n.Soundex, DoubleMetaphone: n.DoubleMetaphone,
+			MaleWeight: n.MaleWeight, FemaleWeight: n.FemaleWeight,
+		})
+	}
+	return exported
+}
+
+// createExportNames creates the names exported by the tests, and a deleted one that is never exported
+func createExportNames(t *testing.T) {
+	t.Helper()
+	male, female := float32(0.25), float32(0.75)
+	names := []models.NameType{
+		{Name: "ANA", Classification: models.ClassificationFemale, NameVariations: "|ANITA|ANNA|"},
+		{Name: "JOAO", Classification: models.ClassificationMale},
+		{Name: "DARCI", Classification: models.ClassificationUnisex, NameVariations: "|DARCY|", MaleWeight: &male, FemaleWeight: &female},
+		{Name: "ALEX", Classification: models.ClassificationUnknown},
+		{Name: "DELETED", Classification: models.ClassificationMale},
+	}
+	for i := range names {
+		if err := names[i].CreateName(); err != nil {
+			t.Fatalf("error creating name: %v", err)
+		}
+	}
+	if err := names[len(names)-1].DeleteName(); err != nil {
+		t.Fatalf("error deleting name: %v", err)
+	}
+}
+
+// An export imported into an empty database recreates the names with their variations, weights and codes
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{database.FormatCSV, database.FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			source := dbtest.Open(t)
+			createExportNames(t)
+			var file bytes.Buffer
+			written, err := database.ExportNames(source, &file, database.ExportOptions{Format: format})
+			if err != nil || written != 4 {
+				t.Fatalf("ExportNames() = %d, %v, want 4 names", written, err)
+			}
+
+			target := dbtest.Open(t)
+			report, err := database.ImportNames(target, &file, database.ImportOptions{Format: format, Mode: database.ImportSkip})
+			if err != nil || report.Inserted != 4 || report.Failed != 0 {
+				t.Fatalf("ImportNames() = %+v, %v, want 4 inserted", report, err)
+			}
+
+			want, got := exportedNames(t, source), exportedNames(t, target)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("imported names = %+v, want %+v", got, want)
+			}
+			var variations int64
+			target.Model(&models.NameVariation{}).Count(&variations)
+			if variations != 3 {
+				t.Fatalf("%d variations imported, want 3", variations)
+			}
+		})
+	}
+}
+
+func TestExportNamesFilters(t *testing.T) {
+	db := dbtest.Open(t)
+	createExportNames(t)
+
+	tests := []struct {
+		name string
+		opts database.ExportOptions
+		want []string
+	}{
+		{"all", database.ExportOptions{}, []string{"ANA", "JOAO", "DARCI", "ALEX"}},
+		{"classification", database.ExportOptions{Classification: models.ClassificationFemale}, []string{"ANA"}},
+		{"metaphone prefix", database.ExportOptions{MetaphonePrefix: "D"}, []string{"DARCI"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Format = database.FormatJSON
+			var file bytes.Buffer
+			if _, err := database.ExportNames(db, &file, tt.opts); err != nil {
+				t.Fatalf("ExportNames() error: %v", err)
+			}
+			var rows []struct{ Name string }
+			if err := json.Unmarshal(file.Bytes(), &rows); err != nil {
+				t.Fatalf("error decoding export %q: %v", file.String(), err)
+			}
+			var got []string
+			for _, row := range rows {
+				got = append(got, row.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("exported %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+package database
+
+// UploadCSVNameTypes exposes uploadCSVNameTypes to the tests of the package
+var UploadCSVNameTypes = uploadCSVNameTypes
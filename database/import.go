@@ -0,0 +1,426 @@
+package database
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/Darklabel91/metaphone-br"
+	"gorm.io/gorm"
+)
+
+// Formats of an import file
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Modes of an import, they decide what happens to the names of the file already on the table
+const (
+	// ImportSkip leaves the names already on the table untouched
+	ImportSkip = "skip"
+	// ImportUpsert updates the names already on the table with the non-empty fields of the file and adds the variations
+	// of the file to theirs
+	ImportUpsert = "upsert"
+	// ImportReplace overwrites the names already on the table with the fields of the file, variations included
+	ImportReplace = "replace"
+)
+
+// ImportBatchSize is the number of rows imported by each transaction
+const ImportBatchSize = 1000
+
+// MaxImportErrors bounds the row errors listed on an import report
+const MaxImportErrors = 1000
+
+// ImportOptions controls how the names of a file are imported
+type ImportOptions struct {
+	Format string
+	Mode   string
+	DryRun bool
+	// Source is the source of the variations created by the import
+	Source string
+	// RecordChanges writes the imported names to the change log so the caches of every instance are patched
+	RecordChanges bool
+}
+
+// ErrInvalidFile is returned when the import file can't be parsed at all, such as a CSV without a name column
+var ErrInvalidFile = errors.New("invalid import file")
+
+// ImportRowError is a row of the file that couldn't be imported
+type ImportRowError struct {
+	Line  int
+	Name  string `json:"Name,omitempty"`
+	Error string
+}
+
+// ImportReport is the result of an import. On a dry run the counts are the ones the import would have.
+type ImportReport struct {
+	Mode     string
+	DryRun   bool
+	Rows     int
+	Inserted int
+	Updated  int
+	Skipped  int
+	Failed   int
+	Errors   []ImportRowError
+}
+
+// importRow is a parsed row of an import file. The empty fields of the file are kept apart so upserts leave them
+// unchanged.
+type importRow struct {
+	line              int
+	name              models.NameType
+	hasClassification bool
+}
+
+// nameRow is a name of a JSON or NDJSON file, as exported and imported
+type nameRow struct {
+	Name           string
+	Classification string
+	NameVariations string   `json:"NameVariations,omitempty"`
+	MaleWeight     *float32 `json:"MaleWeight,omitempty"`
+	FemaleWeight   *float32 `json:"FemaleWeight,omitempty"`
+}
+
+// Validate checks the format and the mode of the options
+func (o ImportOptions) Validate() error {
+	if o.Format != FormatCSV && o.Format != FormatNDJSON {
+		return fmt.Errorf("invalid format %q: %w", o.Format, errors.New("format must be csv or ndjson"))
+	}
+	if o.Mode != ImportSkip && o.Mode != ImportUpsert && o.Mode != ImportReplace {
+		return fmt.Errorf("invalid mode %q: %w", o.Mode, errors.New("mode must be skip, upsert or replace"))
+	}
+	return nil
+}
+
+// FormatOf returns the format of a file by its extension, csv when it is unknown
+func FormatOf(fileName string) string {
+	lower := strings.ToLower(fileName)
+	if strings.HasSuffix(lower, ".ndjson") || strings.HasSuffix(lower, ".jsonl") {
+		return FormatNDJSON
+	}
+	return FormatCSV
+}
+
+// ImportNames imports the names of the CSV or NDJSON file read from r into the NameType table. Every batch of rows is
+// imported in its own transaction, so an interrupted import can be run again. Rows that can't be parsed are listed on
+// the report and skipped. The returned error is set when the file can't be read or a batch can't be written.
+func ImportNames(db *gorm.DB, r io.Reader, opts ImportOptions) (ImportReport, error) {
+	report := ImportReport{Mode: opts.Mode, DryRun: opts.DryRun}
+	if err := opts.Validate(); err != nil {
+		return report, err
+	}
+	if opts.Source == "" {
+		opts.Source = models.SourceImport
+	}
+
+	// Rows of the file already read, the last row of a name wins
+	seen := make(map[string]int)
+	batch := make([]importRow, 0, ImportBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := importBatch(db, batch, opts, &report)
+		if err != nil {
+			return fmt.Errorf("error importing lines %d to %d: %w", batch[0].line, batch[len(batch)-1].line, err)
+		}
+		batch = batch[:0]
+		seen = make(map[string]int)
+		return nil
+	}
+	add := func(row importRow, err error) error {
+		report.Rows++
+		if err != nil {
+			report.addError(row.line, row.name.Name, err)
+			return nil
+		}
+		if i, ok := seen[row.name.Name]; ok {
+			batch[i] = row
+			report.Skipped++
+			return nil
+		}
+		seen[row.name.Name] = len(batch)
+		batch = append(batch, row)
+		if len(batch) == ImportBatchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	var err error
+	switch opts.Format {
+	case FormatCSV:
+		err = readCSV(r, add)
+	case FormatNDJSON:
+		err = readNDJSON(r, add)
+	}
+	if err != nil {
+		return report, err
+	}
+	if err = flush(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// addError lists a row error on the report
+func (r *ImportReport) addError(line int, name string, err error) {
+	r.Failed++
+	if len(r.Errors) < MaxImportErrors {
+		r.Errors = append(r.Errors, ImportRowError{Line: line, Name: name, Error: err.Error()})
+	}
+}
+
+// readCSV reads the rows of a CSV file. The header names the columns: name is required, classification,
+// name_variations, male_weight and female_weight are optional and the others are ignored.
+func readCSV(r io.Reader, add func(importRow, error) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: error reading header: %v", ErrInvalidFile, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return fmt.Errorf("%w: name column not found on the header", ErrInvalidFile)
+	}
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		line++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return fmt.Errorf("error reading line %d: %w", line, err)
+			}
+			if err = add(importRow{line: line}, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		row := importRow{line: line}
+		var weights [2]*float32
+		for i, column := range []string{"male_weight", "female_weight"} {
+			if value := field(record, column); value != "" && err == nil {
+				weights[i], err = parseImportWeight(value)
+			}
+		}
+		if err == nil {
+			row, err = newImportRow(line, field(record, "name"), field(record, "classification"), field(record, "name_variations"), weights[0], weights[1])
+		}
+		if err = add(row, err); err != nil {
+			return err
+		}
+	}
+}
+
+// readNDJSON reads the rows of a NDJSON file, one JSON object with the fields of a NameType per line
+func readNDJSON(r io.Reader, add func(importRow, error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var record nameRow
+		row := importRow{line: line}
+		err := json.Unmarshal([]byte(text), &record)
+		if err == nil {
+			row, err = newImportRow(line, record.Name, record.Classification, record.NameVariations, record.MaleWeight, record.FemaleWeight)
+		}
+		if err = add(row, err); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: error reading line %d: %v", ErrInvalidFile, line+1, err)
+	}
+	return nil
+}
+
+// newImportRow validates the fields of a row and computes the phonetic codes of the name as the seeder does
+func newImportRow(line int, name, classification, nameVariations string, maleWeight, femaleWeight *float32) (importRow, error) {
+	row := importRow{line: line}
+	row.name.Name = strings.ToUpper(strings.TrimSpace(name))
+	if row.name.Name == "" {
+		return row, errors.New("name must not be empty")
+	}
+
+	var err error
+	row.hasClassification = strings.TrimSpace(classification) != ""
+	row.name.Classification, err = models.ParseClassification(classification)
+	if err != nil {
+		return row, err
+	}
+
+	variations, err := models.NormalizeVariations(models.SplitVariations(nameVariations))
+	if err != nil {
+		return row, err
+	}
+	row.name.NameVariations = models.JoinVariations(variations)
+
+	row.name.MaleWeight, row.name.FemaleWeight = maleWeight, femaleWeight
+	if err = row.name.ValidateWeights(); err != nil {
+		return row, err
+	}
+
+	row.name.Metaphone = metaphone.Pack(name)
+	row.name.EncodeName()
+
+	return row, nil
+}
+
+// parseImportWeight parses a weight column of a CSV row
+func parseImportWeight(value string) (*float32, error) {
+	weight, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid weight %q", value)
+	}
+	w := float32(weight)
+	return &w, nil
+}
+
+// importBatch writes a batch of rows in a single transaction and adds its counts to the report. A dry run only
+// counts the rows.
+func importBatch(db *gorm.DB, batch []importRow, opts ImportOptions, report *ImportReport) error {
+	names := make([]string, len(batch))
+	for i, row := range batch {
+		names[i] = row.name.Name
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Get the names already on the table, deleted ones included
+		var existing []models.NameType
+		err := tx.Unscoped().Where("name IN ?", names).Find(&existing).Error
+		if err != nil {
+			return fmt.Errorf("error checking existing names: %w", err)
+		}
+		byName := make(map[string]models.NameType, len(existing))
+		for _, n := range existing {
+			byName[n.Name] = n
+		}
+
+		var inserts, updates []models.NameType
+		for _, row := range batch {
+			current, ok := byName[row.name.Name]
+			switch {
+			case !ok:
+				inserts = append(inserts, row.name)
+			case opts.Mode == ImportSkip:
+				report.Skipped++
+			default:
+				updates = append(updates, mergeImportRow(current, row, opts.Mode))
+			}
+		}
+		report.Inserted += len(inserts)
+		report.Updated += len(updates)
+		if opts.DryRun {
+			return nil
+		}
+
+		// Insert the new names with their variations
+		if len(inserts) != 0 {
+			err = tx.CreateInBatches(&inserts, ImportBatchSize).Error
+			if err != nil {
+				return fmt.Errorf("error creating names: %w", err)
+			}
+			var variations []models.NameVariation
+			for _, n := range inserts {
+				variations = append(variations, models.VariationsOf(n, opts.Source)...)
+			}
+			if len(variations) != 0 {
+				err = tx.CreateInBatches(&variations, ImportBatchSize).Error
+				if err != nil {
+					return fmt.Errorf("error creating name variations: %w", err)
+				}
+			}
+		}
+
+		// Save the updated names, restoring the deleted ones, and replace their variations
+		for i := range updates {
+			err = tx.Unscoped().Save(&updates[i]).Error
+			if err != nil {
+				return fmt.Errorf("error updating name %q: %w", updates[i].Name, err)
+			}
+			if err = updates[i].ReplaceVariations(tx, opts.Source); err != nil {
+				return err
+			}
+		}
+
+		// Check that every name of the batch is on the table
+		var count int64
+		err = tx.Unscoped().Model(&models.NameType{}).Where("name IN ?", names).Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("error counting names: %w", err)
+		}
+		if int(count) != len(batch) {
+			return fmt.Errorf("expected %d names on the table, found %d", len(batch), count)
+		}
+
+		if !opts.RecordChanges {
+			return nil
+		}
+		ids := make([]uint, 0, len(inserts)+len(updates))
+		for _, n := range append(inserts, updates...) {
+			ids = append(ids, n.ID)
+		}
+		return models.RecordChanges(tx, ids, models.ChangeUpsert)
+	})
+}
+
+// mergeImportRow returns the name on the table updated with the row of the file according to the mode
+func mergeImportRow(current models.NameType, row importRow, mode string) models.NameType {
+	merged := current
+	merged.DeletedAt = gorm.DeletedAt{}
+	merged.Metaphone = row.name.Metaphone
+
+	if mode == ImportReplace {
+		merged.Classification = row.name.Classification
+		merged.NameVariations = row.name.NameVariations
+		merged.MaleWeight, merged.FemaleWeight = row.name.MaleWeight, row.name.FemaleWeight
+		return merged
+	}
+
+	if row.hasClassification {
+		merged.Classification = row.name.Classification
+	}
+	if variations := models.SplitVariations(row.name.NameVariations); len(variations) != 0 {
+		merged.NameVariations = models.JoinVariations(append(models.SplitVariations(current.NameVariations), variations...))
+	}
+	if row.name.MaleWeight != nil {
+		merged.MaleWeight = row.name.MaleWeight
+	}
+	if row.name.FemaleWeight != nil {
+		merged.FemaleWeight = row.name.FemaleWeight
+	}
+	return merged
+}
@@ -0,0 +1,191 @@
+package database_test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+	"gorm.io/gorm"
+)
+
+const importSeed = `name,classification,name_variations
+ANA,F,ANNA
+JOAO,M,
+`
+
+// runImport imports the CSV file with the mode and fails the test on error
+func runImport(t *testing.T, db *gorm.DB, file, mode string, dryRun bool) database.ImportReport {
+	t.Helper()
+	report, err := database.ImportNames(db, strings.NewReader(file), database.ImportOptions{Format: database.FormatCSV, Mode: mode, DryRun: dryRun})
+	if err != nil {
+		t.Fatalf("error importing names: %v", err)
+	}
+	return report
+}
+
+// nameOf returns the name on the table and its variations on the variations table, sorted
+func nameOf(t *testing.T, db *gorm.DB, name string) (models.NameType, []string) {
+	t.Helper()
+	var n models.NameType
+	if err := db.Where("name = ?", name).First(&n).Error; err != nil {
+		t.Fatalf("error getting name %s: %v", name, err)
+	}
+	var variations []string
+	if err := db.Model(&models.NameVariation{}).Where("name_type_id = ?", n.ID).Pluck("variation", &variations).Error; err != nil {
+		t.Fatalf("error getting variations of %s: %v", name, err)
+	}
+	sort.Strings(variations)
+	return n, variations
+}
+
+func TestImportNamesModes(t *testing.T) {
+	const update = `name,classification,name_variations
+ANA,,ANITA
+JOAO,F,JOHN
+PEDRO,M,
+`
+	tests := []struct {
+		mode           string
+		inserted       int
+		updated        int
+		skipped        int
+		classification string
+		variations     []string
+	}{
+		{database.ImportSkip, 1, 0, 2, "F", []string{"ANNA"}},
+		{database.ImportUpsert, 1, 2, 0, "F", []string{"ANITA", "ANNA"}},
+		{database.ImportReplace, 1, 2, 0, models.ClassificationUnknown, []string{"ANITA"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			db := dbtest.Open(t)
+			runImport(t, db, importSeed, database.ImportSkip, false)
+
+			report := runImport(t, db, update, tt.mode, false)
+			if report.Rows != 3 || report.Inserted != tt.inserted || report.Updated != tt.updated || report.Skipped != tt.skipped || report.Failed != 0 {
+				t.Fatalf("report = %+v, want %d inserted, %d updated and %d skipped of 3 rows", report, tt.inserted, tt.updated, tt.skipped)
+			}
+
+			ana, variations := nameOf(t, db, "ANA")
+			if ana.Classification != tt.classification {
+				t.Errorf("ANA classification = %q, want %q", ana.Classification, tt.classification)
+			}
+			if !reflect.DeepEqual(variations, tt.variations) {
+				t.Errorf("ANA variations = %v, want %v", variations, tt.variations)
+			}
+			if ana.NameVariations != models.JoinVariations(tt.variations) {
+				t.Errorf("ANA NameVariations = %q, want %q", ana.NameVariations, models.JoinVariations(tt.variations))
+			}
+			nameOf(t, db, "PEDRO")
+		})
+	}
+}
+
+func TestImportNamesRestoresDeleted(t *testing.T) {
+	db := dbtest.Open(t)
+	runImport(t, db, importSeed, database.ImportSkip, false)
+	ana, _ := nameOf(t, db, "ANA")
+	if err := db.Delete(&ana).Error; err != nil {
+		t.Fatalf("error deleting ANA: %v", err)
+	}
+
+	report := runImport(t, db, "name,classification\nANA,F\n", database.ImportUpsert, false)
+	if report.Updated != 1 {
+		t.Fatalf("report = %+v, want 1 updated", report)
+	}
+	nameOf(t, db, "ANA")
+}
+
+func TestImportNamesDedup(t *testing.T) {
+	db := dbtest.Open(t)
+	file := `name,classification
+ana,M
+JOAO,M
+Ana,F
+`
+	report := runImport(t, db, file, database.ImportSkip, false)
+	if report.Rows != 3 || report.Inserted != 2 || report.Skipped != 1 {
+		t.Fatalf("report = %+v, want 2 inserted and 1 skipped of 3 rows", report)
+	}
+	if ana, _ := nameOf(t, db, "ANA"); ana.Classification != "F" {
+		t.Fatalf("ANA classification = %q, want the last row F", ana.Classification)
+	}
+}
+
+func TestImportNamesDryRun(t *testing.T) {
+	db := dbtest.Open(t)
+	runImport(t, db, importSeed, database.ImportSkip, false)
+
+	report := runImport(t, db, "name,classification\nANA,M\nPEDRO,M\n", database.ImportReplace, true)
+	if !report.DryRun || report.Inserted != 1 || report.Updated != 1 {
+		t.Fatalf("report = %+v, want a dry run with 1 inserted and 1 updated", report)
+	}
+
+	var count int64
+	db.Model(&models.NameType{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("%d names on the table, want 2", count)
+	}
+	if ana, _ := nameOf(t, db, "ANA"); ana.Classification != "F" {
+		t.Fatalf("ANA classification = %q, want it unchanged F", ana.Classification)
+	}
+}
+
+func TestImportNamesInvalidRows(t *testing.T) {
+	db := dbtest.Open(t)
+	file := `name,classification,name_variations,male_weight
+ANA,F,,
+,M,,
+JOAO,Z,,
+PEDRO,M,P3DRO,
+MARIA,F,,-1
+`
+	report := runImport(t, db, file, database.ImportSkip, false)
+	if report.Rows != 5 || report.Inserted != 1 || report.Failed != 4 || len(report.Errors) != 4 {
+		t.Fatalf("report = %+v, want 1 inserted and 4 failed of 5 rows", report)
+	}
+	for i, line := range []int{3, 4, 5, 6} {
+		if report.Errors[i].Line != line {
+			t.Errorf("error %d on line %d, want line %d", i, report.Errors[i].Line, line)
+		}
+	}
+}
+
+func TestImportNamesNDJSON(t *testing.T) {
+	db := dbtest.Open(t)
+	file := `{"Name":"ana","Classification":"F","NameVariations":"ANNA|ANITA"}
+
+{"Name":"JOAO","MaleWeight":0.9}
+not json
+`
+	report, err := database.ImportNames(db, strings.NewReader(file), database.ImportOptions{Format: database.FormatNDJSON, Mode: database.ImportUpsert})
+	if err != nil {
+		t.Fatalf("error importing names: %v", err)
+	}
+	if report.Inserted != 2 || report.Failed != 1 || report.Errors[0].Line != 4 {
+		t.Fatalf("report = %+v, want 2 inserted and line 4 failed", report)
+	}
+	if _, variations := nameOf(t, db, "ANA"); !reflect.DeepEqual(variations, []string{"ANITA", "ANNA"}) {
+		t.Fatalf("ANA variations = %v, want [ANITA ANNA]", variations)
+	}
+}
+
+func TestImportNamesInvalidFile(t *testing.T) {
+	db := dbtest.Open(t)
+	for _, file := range []string{"", "classification,name_variations\nF,\n"} {
+		_, err := database.ImportNames(db, strings.NewReader(file), database.ImportOptions{Format: database.FormatCSV, Mode: database.ImportSkip})
+		if !errors.Is(err, database.ErrInvalidFile) {
+			t.Errorf("database.ImportNames(%q) error = %v, want database.ErrInvalidFile", file, err)
+		}
+	}
+
+	_, err := database.ImportNames(db, strings.NewReader(importSeed), database.ImportOptions{Format: database.FormatCSV, Mode: "merge"})
+	if err == nil {
+		t.Error("database.ImportNames with mode merge succeeded, want an error")
+	}
+}
@@ -0,0 +1,491 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Darklabel91/API_Names/models"
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned change of the database schema. Migrations are applied in the order of their versions and
+// are never edited once released: a change of the schema is a new migration.
+//
+// The structs of a migration are snapshots of the models at its version, so it keeps creating the same schema as the
+// models evolve. Up only adds what is missing, so the migrations can be applied to a database created before they
+// existed.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationState is a migration with the time it was applied to the database
+type MigrationState struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time `json:"AppliedAt,omitempty"`
+}
+
+// ErrSchemaBehind is returned when the database schema is missing migrations
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Migrations are the migrations of the database schema, ordered by version
+var Migrations = []Migration{
+	{Version: 1, Name: "create_name_types_users_logs", Up: createInitialTables, Down: dropInitialTables},
+	{Version: 2, Name: "add_user_match_options", Up: addUserMatchOptions, Down: dropUserMatchOptions},
+	{Version: 3, Name: "create_name_variations", Up: createNameVariations, Down: dropNameVariations},
+	{Version: 4, Name: "add_name_weights", Up: addNameWeights, Down: dropNameWeights},
+	{Version: 5, Name: "add_phonetic_codes", Up: addPhoneticCodes, Down: dropPhoneticCodes},
+	{Version: 6, Name: "create_name_changes", Up: createNameChanges, Down: dropNameChanges},
+	{Version: 7, Name: "create_seeds", Up: createSeeds, Down: dropSeeds},
+	{Version: 8, Name: "add_user_roles", Up: addUserRoles, Down: dropUserRoles},
+	{Version: 9, Name: "create_api_keys", Up: createAPIKeys, Down: dropAPIKeys},
+	{Version: 10, Name: "create_refresh_and_revoked_tokens", Up: createSessionTokens, Down: dropSessionTokens},
+}
+
+// MigrateUp applies the pending migrations in order, each one in its own transaction, and returns the ones applied.
+// MySQL commits the schema changes of a migration immediately, so a failed migration may have to be cleaned up there.
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		start := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("error applying migration %04d %s: %v", m.Version, m.Name, err)
+		}
+		log.Printf("-	Applied migration %04d %s %s", m.Version, m.Name, time.Since(start).String())
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the given number of applied migrations, latest first, and returns the ones reverted
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(Migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		if !states[i].Applied {
+			continue
+		}
+
+		m := Migrations[i]
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&models.SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("error reverting migration %04d %s: %v", m.Version, m.Name, err)
+		}
+		log.Printf("-	Reverted migration %04d %s", m.Version, m.Name)
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// MigrationStatus returns the state of every migration, ordered by version
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	// The migrations table is the only one created outside of the migrations
+	err := db.AutoMigrate(&models.SchemaMigration{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating migrations table: %v", err)
+	}
+
+	var applied []models.SchemaMigration
+	err = db.Find(&applied).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting applied migrations: %v", err)
+	}
+	appliedAt := make(map[uint]time.Time, len(applied))
+	for _, a := range applied {
+		appliedAt[a.Version] = a.AppliedAt
+	}
+
+	states := make([]MigrationState, len(Migrations))
+	for i, m := range Migrations {
+		states[i] = MigrationState{Version: m.Version, Name: m.Name}
+		if t, ok := appliedAt[m.Version]; ok {
+			states[i].Applied, states[i].AppliedAt = true, &t
+		}
+	}
+	return states, nil
+}
+
+// PendingMigrations returns the migrations not applied to the database, ordered by version
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for i, s := range states {
+		if !s.Applied {
+			pending = append(pending, Migrations[i])
+		}
+	}
+	return pending, nil
+}
+
+// checkSchema returns ErrSchemaBehind if a migration is not applied to the database
+func checkSchema(db *gorm.DB) error {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations, starting with %04d %s", ErrSchemaBehind, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// Version 1
+
+type nameTypeV1 struct {
+	gorm.Model
+	Name           string `gorm:"unique"`
+	Classification string
+	Metaphone      string `gorm:"index"`
+	NameVariations string
+}
+
+func (nameTypeV1) TableName() string { return "name_types" }
+
+type userV1 struct {
+	gorm.Model
+	Email    string `gorm:"unique"`
+	Password string
+	IP       string
+}
+
+func (userV1) TableName() string { return "users" }
+
+type logV1 struct {
+	gorm.Model
+	Time    string
+	Status  string
+	Latency string
+	IP      string
+	Method  string
+	Path    string
+}
+
+func (logV1) TableName() string { return "logs" }
+
+func createInitialTables(tx *gorm.DB) error {
+	return tx.AutoMigrate(&nameTypeV1{}, &userV1{}, &logV1{})
+}
+
+func dropInitialTables(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&logV1{}, &userV1{}, &nameTypeV1{})
+}
+
+// Version 2
+
+type userV2 struct {
+	MatchThreshold     *float32
+	MatchFallback      *bool
+	MatchMaxRelaxation *float32
+}
+
+func (userV2) TableName() string { return "users" }
+
+func addUserMatchOptions(tx *gorm.DB) error {
+	return tx.AutoMigrate(&userV2{})
+}
+
+func dropUserMatchOptions(tx *gorm.DB) error {
+	return dropColumns(tx, &userV2{}, &userV1{}, "MatchThreshold", "MatchFallback", "MatchMaxRelaxation")
+}
+
+// Version 3
+
+type nameTypeV3 struct {
+	ID             uint `gorm:"primarykey"`
+	NameVariations string
+	Variations     []nameVariationV3 `gorm:"foreignKey:NameTypeID;constraint:OnDelete:CASCADE"`
+}
+
+func (nameTypeV3) TableName() string { return "name_types" }
+
+type nameVariationV3 struct {
+	ID         uint   `gorm:"primarykey"`
+	NameTypeID uint   `gorm:"not null;uniqueIndex:idx_name_variation"`
+	Variation  string `gorm:"size:255;not null;index;uniqueIndex:idx_name_variation"`
+	Source     string `gorm:"size:64"`
+	CreatedAt  time.Time
+}
+
+func (nameVariationV3) TableName() string { return "name_variations" }
+
+// createNameVariations creates the name_variations table and splits the NameVariations string of every name into it,
+// unless it already has variations
+func createNameVariations(tx *gorm.DB) error {
+	err := tx.AutoMigrate(&nameTypeV3{}, &nameVariationV3{})
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	err = tx.Model(&nameVariationV3{}).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+
+	var nameTypes []nameTypeV3
+	return tx.Model(&nameTypeV3{}).Where("deleted_at IS NULL").FindInBatches(&nameTypes, 1000, func(_ *gorm.DB, _ int) error {
+		var variations []nameVariationV3
+		for _, n := range nameTypes {
+			seen := make(map[string]bool)
+			for _, v := range models.SplitVariations(n.NameVariations) {
+				if !seen[v] {
+					seen[v] = true
+					variations = append(variations, nameVariationV3{NameTypeID: n.ID, Variation: v, Source: models.SourceCSV})
+				}
+			}
+		}
+		if len(variations) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&variations, 1000).Error
+	}).Error
+}
+
+func dropNameVariations(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&nameVariationV3{})
+}
+
+// Version 4
+
+type nameTypeV4 struct {
+	MaleWeight   *float32
+	FemaleWeight *float32
+}
+
+func (nameTypeV4) TableName() string { return "name_types" }
+
+func addNameWeights(tx *gorm.DB) error {
+	return tx.AutoMigrate(&nameTypeV4{})
+}
+
+func dropNameWeights(tx *gorm.DB) error {
+	return dropColumns(tx, &nameTypeV4{}, &nameTypeV1{}, "MaleWeight", "FemaleWeight")
+}
+
+// Version 5
+
+type nameTypeV5 struct {
+	Soundex         string `gorm:"size:16;index"`
+	DoubleMetaphone string `gorm:"size:64;index"`
+	SpanishKey      string `gorm:"size:64;index"`
+}
+
+func (nameTypeV5) TableName() string { return "name_types" }
+
+// nameCodesV5 are the columns of name_types read to encode a name
+type nameCodesV5 struct {
+	ID        uint
+	Name      string
+	Metaphone string
+}
+
+func (nameCodesV5) TableName() string { return "name_types" }
+
+type userV5 struct {
+	MatchAlgorithm *string `gorm:"size:32"`
+}
+
+func (userV5) TableName() string { return "users" }
+
+// addPhoneticCodes adds the code columns of the phonetic algorithms and encodes the names missing one of them
+func addPhoneticCodes(tx *gorm.DB) error {
+	err := tx.AutoMigrate(&nameTypeV5{}, &userV5{})
+	if err != nil {
+		return err
+	}
+
+	var nameTypes []nameCodesV5
+	return tx.Model(&nameCodesV5{}).
+		Where("COALESCE(metaphone, '') = '' OR COALESCE(soundex, '') = '' OR COALESCE(double_metaphone, '') = '' OR COALESCE(spanish_key, '') = ''").
+		FindInBatches(&nameTypes, 1000, func(_ *gorm.DB, _ int) error {
+			for _, n := range nameTypes {
+				encoded := models.NameType{Name: n.Name, Metaphone: n.Metaphone}
+				encoded.EncodeName()
+				err := tx.Model(&nameCodesV5{}).Where("id = ?", n.ID).UpdateColumns(map[string]interface{}{
+					"metaphone":        encoded.Metaphone,
+					"soundex":          encoded.Soundex,
+					"double_metaphone": encoded.DoubleMetaphone,
+					"spanish_key":      encoded.SpanishKey,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+func dropPhoneticCodes(tx *gorm.DB) error {
+	err := dropColumns(tx, &userV5{}, &userV1{}, "MatchAlgorithm")
+	if err != nil {
+		return err
+	}
+	return dropColumns(tx, &nameTypeV5{}, &nameTypeV1{}, "Soundex", "DoubleMetaphone", "SpanishKey")
+}
+
+// Version 6
+
+type nameChangeV6 struct {
+	ID         uint      `gorm:"primarykey"`
+	NameTypeID uint      `gorm:"not null;index"`
+	Operation  string    `gorm:"size:16;not null"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (nameChangeV6) TableName() string { return "name_changes" }
+
+func createNameChanges(tx *gorm.DB) error {
+	return tx.AutoMigrate(&nameChangeV6{})
+}
+
+func dropNameChanges(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&nameChangeV6{})
+}
+
+// Version 7
+
+type seedV7 struct {
+	ID        uint   `gorm:"primarykey"`
+	File      string `gorm:"size:255;uniqueIndex"`
+	Rows      int
+	CreatedAt time.Time
+}
+
+func (seedV7) TableName() string { return "seeds" }
+
+func createSeeds(tx *gorm.DB) error {
+	return tx.AutoMigrate(&seedV7{})
+}
+
+func dropSeeds(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&seedV7{})
+}
+
+// Version 8
+
+type userV8 struct {
+	Role string `gorm:"size:16;not null;default:reader"`
+}
+
+func (userV8) TableName() string { return "users" }
+
+// addUserRoles adds the role of the users, readers by default, and makes the root user the first admin
+func addUserRoles(tx *gorm.DB) error {
+	err := tx.AutoMigrate(&userV8{})
+	if err != nil {
+		return err
+	}
+	return tx.Model(&userV8{}).Where("id = ?", models.RootUserID).Update("role", models.RoleAdmin).Error
+}
+
+func dropUserRoles(tx *gorm.DB) error {
+	return dropColumns(tx, &userV8{}, &userV1{}, "Role")
+}
+
+// Version 9
+
+type apiKeyV9 struct {
+	ID         uint   `gorm:"primarykey"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"size:255"`
+	Prefix     string `gorm:"size:16"`
+	Hash       string `gorm:"size:64;not null;uniqueIndex"`
+	Scope      string `gorm:"size:16;not null"`
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+func (apiKeyV9) TableName() string { return "api_keys" }
+
+func createAPIKeys(tx *gorm.DB) error {
+	return tx.AutoMigrate(&apiKeyV9{})
+}
+
+func dropAPIKeys(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&apiKeyV9{})
+}
+
+// Version 10
+
+type refreshTokenV10 struct {
+	ID              uint      `gorm:"primarykey"`
+	UserID          uint      `gorm:"not null;index"`
+	SessionID       string    `gorm:"size:64;not null;index"`
+	Hash            string    `gorm:"size:64;not null;uniqueIndex"`
+	AccessJTI       string    `gorm:"size:64;not null"`
+	AccessExpiresAt time.Time `gorm:"not null"`
+	ExpiresAt       time.Time `gorm:"not null;index"`
+	UsedAt          *time.Time
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+func (refreshTokenV10) TableName() string { return "refresh_tokens" }
+
+type revokedTokenV10 struct {
+	JTI       string    `gorm:"primarykey;size:64"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+func (revokedTokenV10) TableName() string { return "revoked_tokens" }
+
+func createSessionTokens(tx *gorm.DB) error {
+	return tx.AutoMigrate(&refreshTokenV10{}, &revokedTokenV10{})
+}
+
+func dropSessionTokens(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&refreshTokenV10{}, &revokedTokenV10{})
+}
+
+// dropColumns drops the given fields of the model and their indexes, skipping the ones already dropped. SQLite drops a
+// column by copying its table, which loses the other indexes of the table, so the indexes of base, the model the
+// table was created with, are created again.
+func dropColumns(tx *gorm.DB, model, base interface{}, fields ...string) error {
+	migrator := tx.Migrator()
+	for _, field := range fields {
+		if migrator.HasIndex(model, field) {
+			if err := migrator.DropIndex(model, field); err != nil {
+				return err
+			}
+		}
+		if migrator.HasColumn(model, field) {
+			if err := migrator.DropColumn(model, field); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.AutoMigrate(base)
+}
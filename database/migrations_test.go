@@ -0,0 +1,114 @@
+package database_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+func TestMigrateDownAndUp(t *testing.T) {
+	db := dbtest.Open(t)
+	if pending, err := database.PendingMigrations(db); err != nil || len(pending) != 0 {
+		t.Fatalf("PendingMigrations() after MigrateUp = %d, %v, want none", len(pending), err)
+	}
+
+	reverted, err := database.MigrateDown(db, len(database.Migrations))
+	if err != nil {
+		t.Fatalf("error reverting migrations: %v", err)
+	}
+	latest := database.Migrations[len(database.Migrations)-1].Version
+	if len(reverted) != len(database.Migrations) || reverted[0].Version != latest {
+		t.Fatalf("reverted %d migrations starting with %d, want %d starting with %d", len(reverted), reverted[0].Version, len(database.Migrations), latest)
+	}
+	for _, table := range []string{"name_types", "users", "logs", "name_variations", "name_changes", "seeds", "api_keys", "refresh_tokens", "revoked_tokens"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s still exists after reverting every migration", table)
+		}
+	}
+
+	applied, err := database.MigrateUp(db)
+	if err != nil {
+		t.Fatalf("error applying migrations again: %v", err)
+	}
+	if len(applied) != len(database.Migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(database.Migrations))
+	}
+	if applied, err = database.MigrateUp(db); err != nil || len(applied) != 0 {
+		t.Fatalf("second MigrateUp() = %d, %v, want none applied", len(applied), err)
+	}
+}
+
+func TestMigrateDownSteps(t *testing.T) {
+	db := dbtest.Open(t)
+	if _, err := database.MigrateDown(db, 2); err != nil {
+		t.Fatalf("error reverting migrations: %v", err)
+	}
+
+	pending, err := database.PendingMigrations(db)
+	if err != nil || len(pending) != 2 || pending[0].Version != database.Migrations[len(database.Migrations)-2].Version {
+		t.Fatalf("PendingMigrations() = %v, %v, want the 2 latest", pending, err)
+	}
+
+	states, err := database.MigrationStatus(db)
+	if err != nil || len(states) != len(database.Migrations) {
+		t.Fatalf("MigrationStatus() = %d states, %v, want %d", len(states), err, len(database.Migrations))
+	}
+	for i, s := range states {
+		if applied := i < len(states)-2; s.Applied != applied || (s.AppliedAt != nil) != applied {
+			t.Errorf("migration %d applied = %v at %v, want %v", s.Version, s.Applied, s.AppliedAt, applied)
+		}
+	}
+}
+
+// A database created by AutoMigrate before the migrations existed is adopted by MigrateUp
+func TestMigrateUpAdoptsAutoMigratedDatabase(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverSQLite)
+	t.Setenv("DB_NAME", filepath.Join(t.TempDir(), "names.db"))
+	db, err := database.OpenDB()
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	err = db.AutoMigrate(&models.NameType{}, &models.NameVariation{}, &models.NameChange{}, &models.Seed{}, &models.User{}, &models.Log{})
+	if err != nil {
+		t.Fatalf("error automigrating tables: %v", err)
+	}
+	if err = db.Create(&models.NameType{Name: "MARIA", Classification: models.ClassificationFemale}).Error; err != nil {
+		t.Fatalf("error creating name: %v", err)
+	}
+
+	pending, err := database.PendingMigrations(db)
+	if err != nil || len(pending) != len(database.Migrations) {
+		t.Fatalf("PendingMigrations() = %d, %v, want every migration", len(pending), err)
+	}
+	if _, err = database.MigrateUp(db); err != nil {
+		t.Fatalf("error adopting database: %v", err)
+	}
+
+	// The rows are kept
+	var count int64
+	db.Model(&models.NameType{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("name_types has %d rows after MigrateUp, want 1", count)
+	}
+}
+
+// ConnectDB refuses a database with pending migrations unless DB_AUTO_MIGRATE is set
+func TestConnectDBSchemaBehind(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverSQLite)
+	t.Setenv("DB_NAME", filepath.Join(t.TempDir(), "names.db"))
+	t.Setenv("DB_AUTO_MIGRATE", "false")
+
+	if _, err := database.ConnectDB(); !errors.Is(err, database.ErrSchemaBehind) {
+		t.Fatalf("ConnectDB() error = %v, want ErrSchemaBehind", err)
+	}
+}
@@ -0,0 +1,129 @@
+package database_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+	"gorm.io/gorm"
+)
+
+// writeSeedFile writes a CSV seed file with the given names, one per row, and returns its path
+func writeSeedFile(t *testing.T, dir string, names []string) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("name,classification,parse,name_variations\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s,M,%s,|%s|\n", name, name, name)
+	}
+	file := filepath.Join(dir, "name_types.csv")
+	if err := os.WriteFile(file, []byte(b.String()), 0o600); err != nil {
+		t.Fatalf("error writing seed file: %v", err)
+	}
+	return file
+}
+
+// seedNames returns n distinct names, made only of letters like the names of the seed
+func seedNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		name := []byte("NOME")
+		for j := i; j > 0 || len(name) == 4; j /= 26 {
+			name = append(name, byte('A'+j%26))
+		}
+		names[i] = string(name)
+	}
+	return names
+}
+
+// seedCounts returns the names on the table, the distinct ones and the seeds recorded
+func seedCounts(t *testing.T, db *gorm.DB) (names, distinct, seeds int64) {
+	t.Helper()
+	if err := db.Unscoped().Model(&models.NameType{}).Count(&names).Error; err != nil {
+		t.Fatalf("error counting names: %v", err)
+	}
+	if err := db.Unscoped().Model(&models.NameType{}).Distinct("name").Count(&distinct).Error; err != nil {
+		t.Fatalf("error counting distinct names: %v", err)
+	}
+	if err := db.Model(&models.Seed{}).Count(&seeds).Error; err != nil {
+		t.Fatalf("error counting seeds: %v", err)
+	}
+	return names, distinct, seeds
+}
+
+// A seed that fails after some batches were committed is resumed by the next run, without duplicating names
+func TestUploadCSVNameTypesResume(t *testing.T) {
+	db := dbtest.Open(t)
+	dir := t.TempDir()
+	names := seedNames(database.ImportBatchSize*2 + 500)
+
+	// A row with an invalid classification after the first batch stops the seed
+	broken := append([]string(nil), names...)
+	broken[database.ImportBatchSize+100] = strings.Replace(broken[database.ImportBatchSize+100], "NOME", "NOME,X", 1)
+	file := writeSeedFile(t, dir, broken)
+	if err := database.UploadCSVNameTypes(db, file); err == nil {
+		t.Fatal("seed of a file with an invalid row succeeded, want an error")
+	}
+	count, _, seeds := seedCounts(t, db)
+	if count == 0 || count >= int64(len(names)) || seeds != 0 {
+		t.Fatalf("interrupted seed left %d names and %d seeds, want some of the %d names and no seed", count, seeds, len(names))
+	}
+
+	// Once the row is fixed the seed imports the missing rows and is recorded
+	file = writeSeedFile(t, dir, names)
+	if err := database.UploadCSVNameTypes(db, file); err != nil {
+		t.Fatalf("error resuming seed: %v", err)
+	}
+	count, distinct, seeds := seedCounts(t, db)
+	if count != int64(len(names)) || distinct != count || seeds != 1 {
+		t.Fatalf("resumed seed left %d names, %d distinct, and %d seeds, want %d names and 1 seed", count, distinct, seeds, len(names))
+	}
+
+	// A recorded seed doesn't run again
+	if err := db.Where("name = ?", names[0]).Delete(&models.NameType{}).Error; err != nil {
+		t.Fatalf("error deleting name: %v", err)
+	}
+	if err := database.UploadCSVNameTypes(db, file); err != nil {
+		t.Fatalf("error running a recorded seed: %v", err)
+	}
+	var kept int64
+	db.Model(&models.NameType{}).Count(&kept)
+	if kept != int64(len(names))-1 {
+		t.Fatalf("recorded seed ran again: %d names, want %d", kept, len(names)-1)
+	}
+}
+
+// The seed fails unless the table ends with a row for every row of the file
+func TestUploadCSVNameTypesCountMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		setup func(db *gorm.DB) error
+	}{
+		{"name repeated on the file", []string{"ANA", "PEDRO", "ANA"}, func(*gorm.DB) error { return nil }},
+		{"name not on the file", []string{"ANA", "PEDRO"}, func(db *gorm.DB) error {
+			n := models.NameType{Name: "MARIA", Classification: models.ClassificationFemale}
+			return n.CreateName()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t)
+			if err := tt.setup(db); err != nil {
+				t.Fatalf("error setting up: %v", err)
+			}
+			err := database.UploadCSVNameTypes(db, writeSeedFile(t, t.TempDir(), tt.names))
+			if err == nil || !strings.Contains(err.Error(), "error verifying upload") {
+				t.Fatalf("seed error = %v, want a verification error", err)
+			}
+			if _, _, seeds := seedCounts(t, db); seeds != 0 {
+				t.Fatalf("failed seed recorded %d seeds, want 0", seeds)
+			}
+		})
+	}
+}
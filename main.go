@@ -2,20 +2,33 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/Darklabel91/API_Names/database"
 	"github.com/Darklabel91/API_Names/models"
 	"github.com/Darklabel91/API_Names/routes"
 )
 
-func init() {
+// connect connects to the database, checking its schema, configures the tokens and loads the root user and the trusted
+// IPs
+func connect() {
 	// Connect to the database.
 	db, err := database.ConnectDB()
 	if err != nil {
-		log.Fatalf("Error connecting to the database: %v", db.Error)
+		log.Fatalf("Error connecting to the database: %v", err)
 	}
 	models.DB = db
 
+	// Configure the tokens and cookies.
+	models.Auth, err = models.AuthConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring tokens: %v", err)
+	}
+	models.Keys, err = models.KeySetFromEnv()
+	if err != nil {
+		log.Fatalf("Error loading signing keys: %v", err)
+	}
+
 	// Create the root user.
 	if err := models.CreateRoot(); err != nil {
 		log.Fatalf("Error creating root user: %v", err)
@@ -32,6 +45,16 @@ func init() {
 }
 
 func main() {
+	// Run the CLI subcommand, if any.
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatalf("Error running %s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	connect()
+
 	// Handle incoming HTTP requests.
 	log.Println("-	Listening and serving...")
 	err := routes.HandleRequests()
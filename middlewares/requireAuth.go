@@ -1,11 +1,13 @@
 package middlewares
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Darklabel91/API_Names/models"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/time/rate"
@@ -16,46 +18,93 @@ const (
 	TokenCookie = "token"
 )
 
+// Schemes of the Authorization header
+const (
+	// BearerScheme carries a JWT token
+	BearerScheme = "Bearer"
+	// APIKeyScheme carries an API key
+	APIKeyScheme = "ApiKey"
+)
+
 const (
 	MaxRequestsPerSecond = 5000
 	MaxThreadsByToken    = 4
 )
 
 // ValidateAuth returns a Gin middleware function that checks for a valid JWT token in the request header or cookie, and aborts the request with a 401 Unauthorized HTTP status code if the token is invalid or has expired.
+// The token is read from the Token header, the "Authorization: Bearer <token>" header or the token cookie, and must be
+// issued by and for models.Auth and signed by a key of models.Keys. An API key on the "Authorization: ApiKey <key>"
+// header is accepted instead of the token.
 func ValidateAuth() gin.HandlerFunc {
 	// Decode/validate the token
 	return func(c *gin.Context) {
-		// Get the token from the header or cookie
-		tokenString := c.GetHeader(TokenHeader)
-		if tokenString == "" {
-			var err error
-			tokenString, err = c.Cookie(TokenCookie)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "error generating cookie"})
-				return
-			}
+		// Authenticate with the API key, if any
+		if key, ok := apiKeyFromHeader(c); ok {
+			validateAPIKey(c, key)
+			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
+		// Get the token from the headers or cookie
+		tokenString := tokenFromRequest(c)
+		if tokenString == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token not found"})
+			return
+		}
 
-			return []byte(os.Getenv("SECRET")), nil
-		})
+		token, err := jwt.Parse(tokenString, models.Keys.Keyfunc, jwt.WithIssuer(models.Auth.Issuer), jwt.WithAudience(models.Auth.Audience))
 
-		if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenExpired):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token expired"})
+			return
+		case errors.Is(err, jwt.ErrTokenInvalidIssuer), errors.Is(err, jwt.ErrTokenInvalidAudience):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token not issued for this API"})
+			return
+		case err != nil:
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "error generating token"})
 			return
 		}
 
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Check the expiration date
-			if float64(time.Now().Unix()) > claims["exp"].(float64) {
+			// Check the expiration date, tokens without expiration are expired
+			exp, _ := claims["exp"].(float64)
+			if float64(time.Now().Unix()) > exp {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token expired"})
 				return
 			}
 
+			// Set the authenticated user id on the context
+			sub, _ := claims["sub"].(string)
+			userID, err := strconv.Atoi(sub)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token subject"})
+				return
+			}
+			c.Set("userID", uint(userID))
+
+			// Reject the revoked tokens, tokens without id can't be revoked
+			jti, _ := claims["jti"].(string)
+			if jti == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token without id, log in again"})
+				return
+			}
+			revoked, err := models.IsTokenRevoked(jti)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on validating token"})
+				return
+			}
+			if revoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+				return
+			}
+
+			// Set the role of the user on the context, tokens without role are readers
+			role, _ := claims["role"].(string)
+			if role == "" {
+				role = models.RoleReader
+			}
+			c.Set("userRole", role)
+
 			// Continue
 			c.Next()
 		} else {
@@ -65,6 +114,83 @@ func ValidateAuth() gin.HandlerFunc {
 	}
 }
 
+// tokenFromRequest returns the JWT token of the Token header, the Authorization header with the Bearer scheme or the
+// token cookie, in this order
+func tokenFromRequest(c *gin.Context) string {
+	if token := c.GetHeader(TokenHeader); token != "" {
+		return token
+	}
+
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if found && strings.EqualFold(scheme, BearerScheme) {
+		return strings.TrimSpace(token)
+	}
+
+	token, _ = c.Cookie(TokenCookie)
+	return token
+}
+
+// apiKeyFromHeader returns the API key of the Authorization header, if it uses the ApiKey scheme
+func apiKeyFromHeader(c *gin.Context) (string, bool) {
+	scheme, key, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, APIKeyScheme) {
+		return "", false
+	}
+	return strings.TrimSpace(key), true
+}
+
+// validateAPIKey authenticates the request with the API key, with the role granted by its scope
+func validateAPIKey(c *gin.Context, key string) {
+	apiKey, u, err := models.AuthenticateAPIKey(key)
+	if errors.Is(err, models.ErrInvalidAPIKey) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or revoked API key"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error on validating API key"})
+		return
+	}
+
+	role := u.Role
+	if role == "" {
+		role = models.RoleReader
+	}
+	c.Set("userID", u.ID)
+	c.Set("userRole", apiKey.Role(role))
+	c.Set("apiKeyID", apiKey.ID)
+
+	// Continue
+	c.Next()
+}
+
+// RequireSession returns a Gin middleware function that aborts the request with a 403 Forbidden HTTP status code if
+// it is authenticated with an API key, so a leaked key can't create or revoke keys. It must run after ValidateAuth.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetUint("apiKeyID") != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this route requires logging in, API keys are not accepted"})
+			return
+		}
+
+		// Continue
+		c.Next()
+	}
+}
+
+// RequireRole returns a Gin middleware function that aborts the request with a 403 Forbidden HTTP status code unless
+// the role of the authenticated user has the permissions of the given role. It must run after ValidateAuth.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.RoleAllows(c.GetString("userRole"), role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this route requires the " + role + " role"})
+			return
+		}
+
+		// Continue
+		c.Next()
+	}
+}
+
 // RateLimit returns a Gin middleware function that limits the rate of requests to prevent DDoS attacks.
 // The rate limit is enforced using a token bucket algorithm.
 func RateLimit() gin.HandlerFunc {
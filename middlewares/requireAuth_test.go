@@ -0,0 +1,261 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/middlewares"
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// useTestSecret signs and verifies the tokens of the test with testSecret
+func useTestSecret(t *testing.T) {
+	t.Helper()
+	t.Setenv("JWT_ALGORITHM", models.AlgorithmHS256)
+	t.Setenv("SECRET", testSecret)
+	ks, err := models.KeySetFromEnv()
+	if err != nil {
+		t.Fatalf("error loading signing keys: %v", err)
+	}
+	keys := models.Keys
+	models.Keys = ks
+	t.Cleanup(func() { models.Keys = keys })
+}
+
+// testClaims returns the claims of a token of the user with the given role, like the login sets them
+func testClaims(t *testing.T, userID uint, role string) jwt.MapClaims {
+	t.Helper()
+	jti, err := models.NewTokenID()
+	if err != nil {
+		t.Fatalf("error generating token id: %v", err)
+	}
+	claims := jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"sub": strconv.Itoa(int(userID)),
+		"jti": jti,
+		"iss": models.Auth.Issuer,
+		"aud": models.Auth.Audience,
+	}
+	if role != "" {
+		claims["role"] = role
+	}
+	return claims
+}
+
+// signToken signs the claims with the test secret
+func signToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	return token
+}
+
+// testToken returns a token of the user with the given role, signed like the login does
+func testToken(t *testing.T, userID uint, role string) string {
+	t.Helper()
+	return signToken(t, testClaims(t, userID, role))
+}
+
+// roleRouter returns a router with a route open to every role, an editor route and an admin route, guarded like the
+// routes of the API
+func roleRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ValidateAuth())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/name/:name", ok)
+	r.POST("/name", middlewares.RequireRole(models.RoleEditor), ok)
+	r.GET("/admin/users", middlewares.RequireRole(models.RoleAdmin), ok)
+	return r
+}
+
+func TestRequireRole(t *testing.T) {
+	dbtest.Open(t)
+	useTestSecret(t)
+	r := roleRouter()
+
+	tests := []struct {
+		role   string
+		method string
+		path   string
+		want   int
+	}{
+		{models.RoleReader, http.MethodGet, "/name/maria", http.StatusOK},
+		{models.RoleReader, http.MethodPost, "/name", http.StatusForbidden},
+		{models.RoleReader, http.MethodGet, "/admin/users", http.StatusForbidden},
+		{models.RoleEditor, http.MethodGet, "/name/maria", http.StatusOK},
+		{models.RoleEditor, http.MethodPost, "/name", http.StatusOK},
+		{models.RoleEditor, http.MethodGet, "/admin/users", http.StatusForbidden},
+		{models.RoleAdmin, http.MethodGet, "/name/maria", http.StatusOK},
+		{models.RoleAdmin, http.MethodPost, "/name", http.StatusOK},
+		{models.RoleAdmin, http.MethodGet, "/admin/users", http.StatusOK},
+		// Tokens without role are readers and unknown roles have no permissions
+		{"", http.MethodGet, "/name/maria", http.StatusOK},
+		{"", http.MethodPost, "/name", http.StatusForbidden},
+		{"root", http.MethodPost, "/name", http.StatusForbidden},
+		{"root", http.MethodGet, "/admin/users", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(middlewares.TokenHeader, testToken(t, 2, tt.role))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireRoleWithoutAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/name", middlewares.RequireRole(models.RoleEditor), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/name", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status without an authenticated role = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestValidateAuthClaims(t *testing.T) {
+	dbtest.Open(t)
+	useTestSecret(t)
+	r := roleRouter()
+
+	revoked := testClaims(t, 2, models.RoleReader)
+	if err := models.RevokeToken(revoked["jti"].(string), 2, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("error revoking token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(jwt.MapClaims)
+		want   int
+	}{
+		{"valid", func(jwt.MapClaims) {}, http.StatusOK},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "other" }, http.StatusUnauthorized},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other" }, http.StatusUnauthorized},
+		{"audience list", func(c jwt.MapClaims) { c["aud"] = []string{"other", models.Auth.Audience} }, http.StatusOK},
+		{"missing issuer", func(c jwt.MapClaims) { delete(c, "iss") }, http.StatusUnauthorized},
+		{"missing audience", func(c jwt.MapClaims) { delete(c, "aud") }, http.StatusUnauthorized},
+		{"missing expiration", func(c jwt.MapClaims) { delete(c, "exp") }, http.StatusUnauthorized},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, http.StatusUnauthorized},
+		{"missing subject", func(c jwt.MapClaims) { delete(c, "sub") }, http.StatusUnauthorized},
+		{"missing id", func(c jwt.MapClaims) { delete(c, "jti") }, http.StatusUnauthorized},
+		{"revoked", func(c jwt.MapClaims) { c["jti"] = revoked["jti"] }, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := testClaims(t, 2, models.RoleReader)
+			tt.change(claims)
+			req := httptest.NewRequest(http.MethodGet, "/name/maria", nil)
+			req.Header.Set(middlewares.TokenHeader, signToken(t, claims))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	// Tokens signed with another secret are rejected
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(t, 2, models.RoleReader)).SignedString([]byte("other-secret"))
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/name/maria", nil)
+	req.Header.Set(middlewares.TokenHeader, token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status of a token of another secret = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestValidateAuthAPIKey(t *testing.T) {
+	dbtest.Open(t)
+	r := roleRouter()
+	r.POST("/user/keys", middlewares.RequireSession(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// createKey creates a key of the given scope owned by a new user with the given role
+	createKey := func(email, role, scope string) (models.APIKey, string) {
+		t.Helper()
+		u := models.User{Email: email, Password: "hash", Role: role}
+		if _, err := u.CreateUser(); err != nil {
+			t.Fatalf("error creating user: %v", err)
+		}
+		apiKey, key, err := u.CreateAPIKey(email, scope)
+		if err != nil {
+			t.Fatalf("error creating API key: %v", err)
+		}
+		return apiKey, key
+	}
+	_, readerWrite := createKey("reader@test.com", models.RoleReader, models.ScopeWrite)
+	_, editorWrite := createKey("editor@test.com", models.RoleEditor, models.ScopeWrite)
+	_, adminRead := createKey("admin@test.com", models.RoleAdmin, models.ScopeRead)
+	revoked, revokedKey := createKey("revoked@test.com", models.RoleEditor, models.ScopeWrite)
+	if err := revoked.Revoke(); err != nil {
+		t.Fatalf("error revoking API key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		want   int
+	}{
+		// A write key owned by a reader acts as a reader
+		{"write key of a reader reads", readerWrite, http.MethodGet, "/name/maria", http.StatusOK},
+		{"write key of a reader can't write", readerWrite, http.MethodPost, "/name", http.StatusForbidden},
+		{"write key of an editor writes", editorWrite, http.MethodPost, "/name", http.StatusOK},
+		{"write key of an editor isn't admin", editorWrite, http.MethodGet, "/admin/users", http.StatusForbidden},
+		// A read key owned by an admin acts as a reader
+		{"read key of an admin can't write", adminRead, http.MethodPost, "/name", http.StatusForbidden},
+		{"read key of an admin isn't admin", adminRead, http.MethodGet, "/admin/users", http.StatusForbidden},
+		{"keys can't manage keys", editorWrite, http.MethodPost, "/user/keys", http.StatusForbidden},
+		{"revoked key", revokedKey, http.MethodGet, "/name/maria", http.StatusUnauthorized},
+		{"unknown key", models.APIKeyPrefix + "unknown", http.MethodGet, "/name/maria", http.StatusUnauthorized},
+		{"empty key", "", http.MethodGet, "/name/maria", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", middlewares.APIKeyScheme+" "+tt.key)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	// The role is the current one of the owner: a reader promoted to editor writes with the same key
+	reader, err := models.GetUserByEmail("reader@test.com")
+	if err != nil {
+		t.Fatalf("error getting user: %v", err)
+	}
+	if err = reader.SetRole(models.RoleEditor); err != nil {
+		t.Fatalf("error setting role: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/name", nil)
+	req.Header.Set("Authorization", middlewares.APIKeyScheme+" "+readerWrite)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status after the promotion = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+}
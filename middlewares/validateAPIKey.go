@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateAPIKeyJSON validates JSON on models.APIKeyInput body. The name is required and the scope must be read or
+// write, read by default
+func ValidateAPIKeyJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.APIKeyInput
+		err := c.ShouldBindJSON(&input)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid JSON request body"})
+			return
+		}
+
+		input.Name = strings.TrimSpace(input.Name)
+		if input.Name == "" || len(input.Name) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "the name of the key must have between 1 and 255 characters"})
+			return
+		}
+
+		if input.Scope == "" {
+			input.Scope = models.ScopeRead
+		}
+		input.Scope, err = models.ParseScope(input.Scope)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set("apiKeyInput", input)
+		c.Next()
+	}
+}
@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateExport validates the optional "format", "classification", "metaphone" and "updated_since" query parameters of
+// an export. The format defaults to csv, the metaphone is a prefix made only of letters and updated_since is a RFC 3339
+// time or a date.
+func ValidateExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := database.ExportOptions{Format: strings.ToLower(c.DefaultQuery("format", database.FormatCSV))}
+		if err := opts.Validate(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if param := c.Query("classification"); param != "" {
+			classification, err := models.ParseClassification(param)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			opts.Classification = classification
+		}
+
+		opts.MetaphonePrefix = strings.ToUpper(strings.TrimSpace(c.Query("metaphone")))
+		for _, r := range opts.MetaphonePrefix {
+			if !unicode.IsLetter(r) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "metaphone must contain only letters"})
+				return
+			}
+		}
+
+		since, err := timeQuery(c, "updated_since")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts.UpdatedSince = since
+
+		c.Set("exportOptions", opts)
+		c.Next()
+	}
+}
+
+// timeQuery parses the optional time query parameter with the given key, a RFC 3339 time or a date. It returns nil when
+// the parameter is not set.
+func timeQuery(c *gin.Context, key string) (*time.Time, error) {
+	param := c.Query(key)
+	if param == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", param)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a RFC 3339 time or a date such as 2023-04-12", key)
+	}
+	return &t, nil
+}
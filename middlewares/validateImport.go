@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Darklabel91/API_Names/database"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateImport validates the multipart "file" field and the optional "mode", "format" and "dryRun" parameters of an
+// import. The mode defaults to skip and the format to the one of the file extension.
+func ValidateImport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "file must be sent on the multipart field file"})
+			return
+		}
+
+		opts := database.ImportOptions{
+			Format:        c.DefaultQuery("format", c.PostForm("format")),
+			Mode:          c.DefaultQuery("mode", c.DefaultPostForm("mode", database.ImportSkip)),
+			RecordChanges: true,
+		}
+		if opts.Format == "" {
+			opts.Format = database.FormatOf(file.Filename)
+		}
+
+		if param := c.DefaultQuery("dryRun", c.PostForm("dryRun")); param != "" {
+			opts.DryRun, err = strconv.ParseBool(param)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "dryRun must be a boolean"})
+				return
+			}
+		}
+
+		if err := opts.Validate(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set("importFile", file)
+		c.Set("importOptions", opts)
+		c.Next()
+	}
+}
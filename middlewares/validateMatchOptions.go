@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers used to echo the match options back to the caller
+const (
+	ThresholdHeader     = "X-Match-Threshold"
+	FallbackHeader      = "X-Match-Fallback"
+	MaxRelaxationHeader = "X-Match-Max-Relaxation"
+	AlgorithmHeader     = "X-Match-Algorithm"
+)
+
+// ValidateMatchOptions is a Gin middleware function that builds the match options of the request. The defaults of the
+// authenticated user are overridden by the optional "threshold", "fallback", "maxRelaxation" and "algorithm" query
+// parameters.
+// The options are validated and echoed back on the response headers so results are reproducible.
+func ValidateMatchOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := models.DefaultMatchOptions()
+
+		// Use the defaults of the authenticated user, cached so the requests don't read the user table
+		if userID := c.GetUint("userID"); userID != 0 {
+			userOpts, err := models.UserMatchOptions(userID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error getting user match options"})
+				return
+			}
+			opts = userOpts
+		}
+
+		// Override them with the query parameters
+		if param, ok := c.GetQuery("threshold"); ok {
+			threshold, err := strconv.ParseFloat(param, 32)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "threshold must be a number"})
+				return
+			}
+			opts.Threshold = float32(threshold)
+		}
+		if param, ok := c.GetQuery("fallback"); ok {
+			fallback, err := strconv.ParseBool(param)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "fallback must be a boolean"})
+				return
+			}
+			opts.Fallback = fallback
+		}
+		if param, ok := c.GetQuery("maxRelaxation"); ok {
+			maxRelaxation, err := strconv.ParseFloat(param, 32)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "maxRelaxation must be a number"})
+				return
+			}
+			opts.MaxRelaxation = float32(maxRelaxation)
+		}
+		if param, ok := c.GetQuery("algorithm"); ok {
+			opts.Algorithm = strings.ToLower(strings.TrimSpace(param))
+		}
+
+		if err := opts.Validate(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Echo the options back
+		c.Header(ThresholdHeader, strconv.FormatFloat(float64(opts.Threshold), 'f', -1, 32))
+		c.Header(FallbackHeader, strconv.FormatBool(opts.Fallback))
+		c.Header(MaxRelaxationHeader, strconv.FormatFloat(float64(opts.MaxRelaxation), 'f', -1, 32))
+		c.Header(AlgorithmHeader, opts.Algorithm)
+
+		c.Set("matchOptions", opts)
+		c.Next()
+	}
+}
@@ -35,7 +35,7 @@ func ValidateName() gin.HandlerFunc {
 	}
 }
 
-//ValidateNameJSON validates JSON on models.NameType body
+//ValidateNameJSON validates JSON on models.NameType body. Classification must be one of the models classifications and weights must not be negative
 func ValidateNameJSON() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var name models.NameType
@@ -44,7 +44,117 @@ func ValidateNameJSON() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid JSON request body"})
 			return
 		}
+
+		// Normalize the classification, an empty one is left empty so updates keep the current value
+		if name.Classification != "" {
+			name.Classification, err = models.ParseClassification(name.Classification)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if err = name.ValidateWeights(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.Set("name", name)
 		c.Next()
 	}
 }
+
+// ValidateBatchJSON validates JSON on models.BatchInput body. It must contain between 1 and models.MaxBatchSize names
+func ValidateBatchJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var batch models.BatchInput
+		err := c.ShouldBindJSON(&batch)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid JSON request body"})
+			return
+		}
+
+		if len(batch.Names) == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Names must contain at least one name"})
+			return
+		}
+
+		if len(batch.Names) > models.MaxBatchSize {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Names must contain at most " + strconv.Itoa(models.MaxBatchSize) + " names"})
+			return
+		}
+
+		c.Set("names", batch.Names)
+		c.Next()
+	}
+}
+
+// ValidateTop validates the optional "top" query parameter. It must be an integer between 1 and models.MaxTopCandidates
+func ValidateTop() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		param, ok := c.GetQuery("top")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		top, err := strconv.Atoi(param)
+		if err != nil || top < 1 || top > models.MaxTopCandidates {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "top must be an integer between 1 and " + strconv.Itoa(models.MaxTopCandidates)})
+			return
+		}
+
+		c.Set("top", top)
+		c.Next()
+	}
+}
+
+// ValidateExplain validates the optional "explain" query parameter. It must be a boolean
+func ValidateExplain() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		param, ok := c.GetQuery("explain")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		explain, err := strconv.ParseBool(param)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "explain must be a boolean"})
+			return
+		}
+
+		c.Set("explain", explain)
+		c.Next()
+	}
+}
+
+// ValidateVariationsJSON validates JSON on models.VariationsInput body. It must contain at least one variation made only of letters
+func ValidateVariationsJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.VariationsInput
+		err := c.ShouldBindJSON(&input)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid JSON request body"})
+			return
+		}
+
+		if len(input.Variations) == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Variations must contain at least one variation"})
+			return
+		}
+
+		input.Variations, err = models.NormalizeVariations(input.Variations)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if input.Source == "" {
+			input.Source = models.SourceAPI
+		}
+
+		c.Set("variations", input)
+		c.Next()
+	}
+}
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateNameList validates the optional query parameters of a listing of the names: "limit" between 1 and
+// models.MaxListLimit, "sort" as a key of models.ListSorts prefixed by "-" for the descending order, "cursor",
+// "classification", "metaphone", "prefix" made only of letters, the "created_after", "created_before", "updated_after"
+// and "updated_before" times and the "include_deleted" boolean.
+func ValidateNameList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := models.ListOptions{
+			Limit:     models.DefaultListLimit,
+			Cursor:    c.Query("cursor"),
+			Metaphone: strings.ToUpper(strings.TrimSpace(c.Query("metaphone"))),
+			Prefix:    strings.ToUpper(strings.TrimSpace(c.Query("prefix"))),
+		}
+
+		if param, ok := c.GetQuery("limit"); ok {
+			limit, err := strconv.Atoi(param)
+			if err != nil || limit < 1 || limit > models.MaxListLimit {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer between 1 and " + strconv.Itoa(models.MaxListLimit)})
+				return
+			}
+			opts.Limit = limit
+		}
+
+		opts.Sort = c.DefaultQuery("sort", "id")
+		if strings.HasPrefix(opts.Sort, "-") {
+			opts.Sort, opts.Desc = opts.Sort[1:], true
+		}
+		if _, ok := models.ListSorts[opts.Sort]; !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "sort must be id, name, created_at or updated_at, prefixed by - for the descending order"})
+			return
+		}
+
+		if param := c.Query("classification"); param != "" {
+			classification, err := models.ParseClassification(param)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			opts.Classification = classification
+		}
+
+		for _, r := range opts.Prefix {
+			if !unicode.IsLetter(r) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "prefix must contain only letters"})
+				return
+			}
+		}
+
+		for key, t := range map[string]**time.Time{
+			"created_after":  &opts.CreatedAfter,
+			"created_before": &opts.CreatedBefore,
+			"updated_after":  &opts.UpdatedAfter,
+			"updated_before": &opts.UpdatedBefore,
+		} {
+			var err error
+			*t, err = timeQuery(c, key)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if param, ok := c.GetQuery("include_deleted"); ok {
+			includeDeleted, err := strconv.ParseBool(param)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "include_deleted must be a boolean"})
+				return
+			}
+			opts.IncludeDeleted = includeDeleted
+		}
+
+		c.Set("listOptions", opts)
+		c.Next()
+	}
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/Darklabel91/API_Names/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateRoleJSON validates JSON on models.RoleInput body. The role must be admin, editor or reader
+func ValidateRoleJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.RoleInput
+		err := c.ShouldBindJSON(&input)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid JSON request body"})
+			return
+		}
+
+		input.Role, err = models.ParseRole(input.Role)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set("roleInput", input)
+		c.Next()
+	}
+}
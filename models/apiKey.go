@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scopes of an API key
+const (
+	// ScopeRead grants the permissions of a reader
+	ScopeRead = "read"
+	// ScopeWrite grants the permissions of an editor
+	ScopeWrite = "write"
+)
+
+// scopeRoles maps the scopes to the roles they grant
+var scopeRoles = map[string]string{
+	ScopeRead:  RoleReader,
+	ScopeWrite: RoleEditor,
+}
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to spot
+const APIKeyPrefix = "nk_"
+
+// APIKeyTouchInterval is how often the last use of an API key is saved, so a busy key doesn't write on every request
+const APIKeyTouchInterval = time.Minute
+
+// ErrInvalidAPIKey is returned when an API key doesn't exist or is revoked
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKey is a long-lived credential of a user for machine-to-machine clients. Only the hash of the key is stored, the
+// key itself is shown once when it is created.
+type APIKey struct {
+	ID     uint   `gorm:"primarykey"`
+	UserID uint   `gorm:"not null;index"`
+	Name   string `gorm:"size:255"`
+	// Prefix is the start of the key, to tell the keys apart
+	Prefix     string     `gorm:"size:16"`
+	Hash       string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scope      string     `gorm:"size:16;not null"`
+	LastUsedAt *time.Time `json:"LastUsedAt,omitempty"`
+	CreatedAt  time.Time
+	RevokedAt  *time.Time `json:"RevokedAt,omitempty"`
+}
+
+// APIKeyInput is the struct for the create API key body
+type APIKeyInput struct {
+	Name  string `json:"Name"`
+	Scope string `json:"Scope"`
+}
+
+// ParseScope returns the scope of the given value, in any case
+func ParseScope(value string) (string, error) {
+	scope := strings.ToLower(strings.TrimSpace(value))
+	if _, ok := scopeRoles[scope]; !ok {
+		return "", fmt.Errorf("invalid scope %q: %w", value, errors.New("scope must be read or write"))
+	}
+	return scope, nil
+}
+
+// Role returns the role granted by the key to a user with the given role: the role of its scope, never above the
+// role of the user
+func (k *APIKey) Role(userRole string) string {
+	role := scopeRoles[k.Scope]
+	if !RoleAllows(userRole, role) {
+		return userRole
+	}
+	return role
+}
+
+// CreateAPIKey creates an API key of the user with the given name and scope. It returns the key, which is not stored.
+func (u *User) CreateAPIKey(name, scope string) (APIKey, string, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return APIKey{}, "", fmt.Errorf("error generating API key: %w", err)
+	}
+	key := APIKeyPrefix + secret
+
+	apiKey := APIKey{
+		UserID: u.ID,
+		Name:   name,
+		Prefix: key[:len(APIKeyPrefix)+8],
+		Hash:   hashToken(key),
+		Scope:  scope,
+	}
+	err = DB.Create(&apiKey).Error
+	if err != nil {
+		return APIKey{}, "", fmt.Errorf("error creating API key: %w", err)
+	}
+	return apiKey, key, nil
+}
+
+// GetAPIKeys returns the API keys of the user with the given id, revoked ones included, ordered by id
+func GetAPIKeys(userID uint) ([]APIKey, error) {
+	var keys []APIKey
+	err := DB.Where("user_id = ?", userID).Order("id").Find(&keys).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// GetAPIKeyByID returns the API key with the given id
+func GetAPIKeyByID(id uint) (APIKey, error) {
+	var key APIKey
+	err := DB.Where("id = ?", id).Limit(1).Find(&key).Error
+	if err != nil {
+		return APIKey{}, fmt.Errorf("error getting API key by id: %w", err)
+	}
+	if key.ID == 0 {
+		return APIKey{}, fmt.Errorf("error getting API key by id: %w", errors.New("API key not found on the database"))
+	}
+	return key, nil
+}
+
+// Revoke revokes the API key, it can't be used anymore
+func (k *APIKey) Revoke() error {
+	if k.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	err := DB.Model(k).Update("revoked_at", now).Error
+	if err != nil {
+		return fmt.Errorf("error revoking API key: %w", err)
+	}
+	k.RevokedAt = &now
+	return nil
+}
+
+// AuthenticateAPIKey returns the non-revoked API key matching the given key and its user, and saves its last use
+func AuthenticateAPIKey(key string) (APIKey, User, error) {
+	var apiKey APIKey
+	err := DB.Where("hash = ? AND revoked_at IS NULL", hashToken(key)).Limit(1).Find(&apiKey).Error
+	if err != nil {
+		return APIKey{}, User{}, fmt.Errorf("error getting API key: %w", err)
+	}
+	if apiKey.ID == 0 {
+		return APIKey{}, User{}, ErrInvalidAPIKey
+	}
+
+	u, err := GetUserByID(apiKey.UserID)
+	if err != nil {
+		return APIKey{}, User{}, ErrInvalidAPIKey
+	}
+
+	// Save the last use at most once per APIKeyTouchInterval
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > APIKeyTouchInterval {
+		err = DB.Model(&apiKey).Update("last_used_at", now).Error
+		if err != nil {
+			return APIKey{}, User{}, fmt.Errorf("error updating API key last use: %w", err)
+		}
+	}
+
+	return apiKey, u, nil
+}
@@ -0,0 +1,165 @@
+package models_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+func TestAPIKeyRole(t *testing.T) {
+	tests := []struct {
+		scope, userRole, want string
+	}{
+		{models.ScopeRead, models.RoleReader, models.RoleReader},
+		{models.ScopeRead, models.RoleEditor, models.RoleReader},
+		{models.ScopeRead, models.RoleAdmin, models.RoleReader},
+		// A write key never grants more than the current role of its user
+		{models.ScopeWrite, models.RoleReader, models.RoleReader},
+		{models.ScopeWrite, models.RoleEditor, models.RoleEditor},
+		{models.ScopeWrite, models.RoleAdmin, models.RoleEditor},
+	}
+	for _, tt := range tests {
+		k := models.APIKey{Scope: tt.scope}
+		if got := k.Role(tt.userRole); got != tt.want {
+			t.Errorf("%s key of a %s has role %s, want %s", tt.scope, tt.userRole, got, tt.want)
+		}
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		value, want string
+		wantErr     bool
+	}{
+		{"read", models.ScopeRead, false},
+		{" WRITE ", models.ScopeWrite, false},
+		{"", "", true},
+		{"admin", "", true},
+		{"read,write", "", true},
+	}
+	for _, tt := range tests {
+		got, err := models.ParseScope(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseScope(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// createTestUser creates a user with the given role
+func createTestUser(t *testing.T, email, role string) models.User {
+	t.Helper()
+	u := models.User{Email: email, Password: "hash", Role: role}
+	if _, err := u.CreateUser(); err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	return u
+}
+
+func TestCreateAPIKeyStoresHash(t *testing.T) {
+	db := dbtest.Open(t)
+	u := createTestUser(t, "reader@test.com", models.RoleReader)
+
+	apiKey, key, err := u.CreateAPIKey("crawler", models.ScopeRead)
+	if err != nil {
+		t.Fatalf("error creating API key: %v", err)
+	}
+	if !strings.HasPrefix(key, models.APIKeyPrefix) || !strings.HasPrefix(key, apiKey.Prefix) || len(apiKey.Prefix) >= len(key) {
+		t.Fatalf("key %q with prefix %q, want a key starting with %q and its prefix", key, apiKey.Prefix, models.APIKeyPrefix)
+	}
+
+	// Only the SHA-256 of the key is stored
+	sum := sha256.Sum256([]byte(key))
+	var stored models.APIKey
+	db.First(&stored, apiKey.ID)
+	if stored.Hash != hex.EncodeToString(sum[:]) {
+		t.Fatalf("stored hash = %q, want the SHA-256 of the key", stored.Hash)
+	}
+	var leaks int64
+	db.Model(&models.APIKey{}).Where("hash = ? OR prefix = ? OR name = ?", key, key, key).Count(&leaks)
+	if leaks != 0 {
+		t.Fatal("the key is stored on the api_keys table")
+	}
+
+	// And it authenticates the key
+	got, owner, err := models.AuthenticateAPIKey(key)
+	if err != nil || got.ID != apiKey.ID || owner.ID != u.ID {
+		t.Fatalf("AuthenticateAPIKey() = key %d of user %d, %v, want key %d of user %d", got.ID, owner.ID, err, apiKey.ID, u.ID)
+	}
+}
+
+func TestAuthenticateAPIKeyInvalid(t *testing.T) {
+	dbtest.Open(t)
+	u := createTestUser(t, "editor@test.com", models.RoleEditor)
+	revoked, revokedKey, err := u.CreateAPIKey("old", models.ScopeWrite)
+	if err != nil {
+		t.Fatalf("error creating API key: %v", err)
+	}
+	if err = revoked.Revoke(); err != nil {
+		t.Fatalf("error revoking API key: %v", err)
+	}
+	_, key, err := u.CreateAPIKey("new", models.ScopeWrite)
+	if err != nil {
+		t.Fatalf("error creating API key: %v", err)
+	}
+
+	for name, k := range map[string]string{
+		"revoked":   revokedKey,
+		"unknown":   models.APIKeyPrefix + "unknown",
+		"truncated": key[:len(key)-1],
+		"empty":     "",
+	} {
+		if _, _, err := models.AuthenticateAPIKey(k); !errors.Is(err, models.ErrInvalidAPIKey) {
+			t.Errorf("AuthenticateAPIKey(%s key) error = %v, want ErrInvalidAPIKey", name, err)
+		}
+	}
+}
+
+func TestAuthenticateAPIKeyTouch(t *testing.T) {
+	db := dbtest.Open(t)
+	u := createTestUser(t, "reader@test.com", models.RoleReader)
+	apiKey, key, err := u.CreateAPIKey("crawler", models.ScopeRead)
+	if err != nil {
+		t.Fatalf("error creating API key: %v", err)
+	}
+	lastUsedAt := func() time.Time {
+		t.Helper()
+		var k models.APIKey
+		db.First(&k, apiKey.ID)
+		if k.LastUsedAt == nil {
+			t.Fatal("last use of the API key not saved")
+		}
+		return *k.LastUsedAt
+	}
+
+	// The first use is saved
+	if _, _, err = models.AuthenticateAPIKey(key); err != nil {
+		t.Fatalf("error authenticating API key: %v", err)
+	}
+	first := lastUsedAt()
+
+	// A use within APIKeyTouchInterval of the last one saved isn't
+	recent := first.Add(-models.APIKeyTouchInterval / 2)
+	db.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("last_used_at", recent)
+	if _, _, err = models.AuthenticateAPIKey(key); err != nil {
+		t.Fatalf("error authenticating API key: %v", err)
+	}
+	if got := lastUsedAt(); !got.Equal(recent) {
+		t.Fatalf("last use = %v, want %v kept within the touch interval", got, recent)
+	}
+
+	// A use after it is
+	old := first.Add(-2 * models.APIKeyTouchInterval)
+	db.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("last_used_at", old)
+	if _, _, err = models.AuthenticateAPIKey(key); err != nil {
+		t.Fatalf("error authenticating API key: %v", err)
+	}
+	if got := lastUsedAt(); !got.After(old.Add(models.APIKeyTouchInterval)) {
+		t.Fatalf("last use = %v, want it saved again after %v", got, old)
+	}
+}
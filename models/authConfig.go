@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the AuthConfig
+const (
+	DefaultAccessTokenTTL  = time.Hour
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+	DefaultTokenIssuer     = "API_Names"
+	DefaultTokenAudience   = "API_Names"
+)
+
+// AuthConfig is the configuration of the JWT tokens and of their cookies
+type AuthConfig struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// CookieMaxAge is the max age of the cookie of the access token, the refresh token cookie lasts RefreshTokenTTL
+	CookieMaxAge   time.Duration
+	CookieSecure   bool
+	CookieSameSite http.SameSite
+	CookieDomain   string
+	// Issuer and Audience are set on the iss and aud claims of the tokens, and required on the tokens received
+	Issuer   string
+	Audience string
+}
+
+// Auth is the configuration of the tokens of the API, set from the environment on startup
+var Auth = AuthConfig{
+	AccessTokenTTL:  DefaultAccessTokenTTL,
+	RefreshTokenTTL: DefaultRefreshTokenTTL,
+	CookieMaxAge:    DefaultAccessTokenTTL,
+	CookieSameSite:  http.SameSiteLaxMode,
+	Issuer:          DefaultTokenIssuer,
+	Audience:        DefaultTokenAudience,
+}
+
+// sameSiteModes are the values of COOKIE_SAMESITE
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
+
+// AuthConfigFromEnv returns the AuthConfig of the environment variables ACCESS_TOKEN_TTL, REFRESH_TOKEN_TTL,
+// COOKIE_MAX_AGE, COOKIE_SECURE, COOKIE_SAMESITE, COOKIE_DOMAIN, JWT_ISSUER and JWT_AUDIENCE. Variables not set keep
+// their defaults, and the cookie max age defaults to the access token TTL.
+func AuthConfigFromEnv() (AuthConfig, error) {
+	cfg := Auth
+
+	var err error
+	if cfg.AccessTokenTTL, err = durationEnv("ACCESS_TOKEN_TTL", DefaultAccessTokenTTL); err != nil {
+		return AuthConfig{}, err
+	}
+	if cfg.RefreshTokenTTL, err = durationEnv("REFRESH_TOKEN_TTL", DefaultRefreshTokenTTL); err != nil {
+		return AuthConfig{}, err
+	}
+	if cfg.CookieMaxAge, err = durationEnv("COOKIE_MAX_AGE", cfg.AccessTokenTTL); err != nil {
+		return AuthConfig{}, err
+	}
+
+	if value := os.Getenv("COOKIE_SECURE"); value != "" {
+		cfg.CookieSecure, err = strconv.ParseBool(value)
+		if err != nil {
+			return AuthConfig{}, fmt.Errorf("error parsing COOKIE_SECURE %q: must be true or false", value)
+		}
+	}
+
+	if value := os.Getenv("COOKIE_SAMESITE"); value != "" {
+		mode, ok := sameSiteModes[strings.ToLower(value)]
+		if !ok {
+			return AuthConfig{}, fmt.Errorf("error parsing COOKIE_SAMESITE %q: must be lax, strict or none", value)
+		}
+		cfg.CookieSameSite = mode
+	}
+	if cfg.CookieSameSite == http.SameSiteNoneMode && !cfg.CookieSecure {
+		return AuthConfig{}, fmt.Errorf("error parsing COOKIE_SAMESITE: none requires COOKIE_SECURE=true")
+	}
+
+	cfg.CookieDomain = os.Getenv("COOKIE_DOMAIN")
+	if value := os.Getenv("JWT_ISSUER"); value != "" {
+		cfg.Issuer = value
+	}
+	if value := os.Getenv("JWT_AUDIENCE"); value != "" {
+		cfg.Audience = value
+	}
+
+	return cfg, nil
+}
+
+// durationEnv returns the duration of the environment variable key, such as 15m or 24h, or def if it is not set
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("error parsing %s %q: must be a positive duration such as 15m or 24h", key, value)
+	}
+	return d, nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAuthConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    AuthConfig
+		wantErr bool
+	}{
+		{"defaults", map[string]string{}, Auth, false},
+		{
+			"values",
+			map[string]string{
+				"ACCESS_TOKEN_TTL": "15m", "REFRESH_TOKEN_TTL": "24h", "COOKIE_MAX_AGE": "30m", "COOKIE_SECURE": "true",
+				"COOKIE_SAMESITE": "None", "COOKIE_DOMAIN": "example.com", "JWT_ISSUER": "issuer", "JWT_AUDIENCE": "audience",
+			},
+			AuthConfig{
+				AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: 24 * time.Hour, CookieMaxAge: 30 * time.Minute,
+				CookieSecure: true, CookieSameSite: http.SameSiteNoneMode, CookieDomain: "example.com",
+				Issuer: "issuer", Audience: "audience",
+			},
+			false,
+		},
+		// The cookie max age follows the access token TTL
+		{
+			"cookie max age of the access token",
+			map[string]string{"ACCESS_TOKEN_TTL": "10m", "COOKIE_SAMESITE": "strict"},
+			AuthConfig{
+				AccessTokenTTL: 10 * time.Minute, RefreshTokenTTL: DefaultRefreshTokenTTL, CookieMaxAge: 10 * time.Minute,
+				CookieSameSite: http.SameSiteStrictMode, Issuer: DefaultTokenIssuer, Audience: DefaultTokenAudience,
+			},
+			false,
+		},
+		{"invalid duration", map[string]string{"ACCESS_TOKEN_TTL": "1 hour"}, AuthConfig{}, true},
+		{"negative duration", map[string]string{"REFRESH_TOKEN_TTL": "-1h"}, AuthConfig{}, true},
+		{"zero duration", map[string]string{"COOKIE_MAX_AGE": "0s"}, AuthConfig{}, true},
+		{"invalid secure", map[string]string{"COOKIE_SECURE": "yes"}, AuthConfig{}, true},
+		{"invalid same site", map[string]string{"COOKIE_SAMESITE": "relaxed"}, AuthConfig{}, true},
+		{"same site none without secure", map[string]string{"COOKIE_SAMESITE": "none"}, AuthConfig{}, true},
+	}
+	keys := []string{
+		"ACCESS_TOKEN_TTL", "REFRESH_TOKEN_TTL", "COOKIE_MAX_AGE", "COOKIE_SECURE", "COOKIE_SAMESITE", "COOKIE_DOMAIN",
+		"JWT_ISSUER", "JWT_AUDIENCE",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range keys {
+				t.Setenv(key, tt.env[key])
+			}
+			cfg, err := AuthConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AuthConfigFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cfg != tt.want {
+				t.Fatalf("AuthConfigFromEnv() = %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"github.com/Darklabel91/Levenshtein"
+)
+
+// bkTree is a Burkhard-Keller tree of words indexed by Levenshtein distance
+type bkTree struct {
+	root *bkNode
+	size int
+}
+
+// bkNode is a word of the tree and its children keyed by their distance to the word
+type bkNode struct {
+	word     string
+	children map[int]*bkNode
+}
+
+// Add inserts a word on the tree. Words already on the tree are ignored.
+func (t *bkTree) Add(word string) {
+	if t.root == nil {
+		t.root = &bkNode{word: word}
+		t.size++
+		return
+	}
+
+	node := t.root
+	for {
+		distance := levenshtein.Distance(node.word, word)
+		if distance == 0 {
+			return
+		}
+
+		child, ok := node.children[distance]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[distance] = &bkNode{word: word}
+			t.size++
+			return
+		}
+		node = child
+	}
+}
+
+// With returns a copy of the tree with the given word inserted. Only the nodes on the path of the word are copied,
+// the rest is shared with t, so t can still be searched concurrently.
+func (t *bkTree) With(word string) *bkTree {
+	if t.root == nil {
+		return &bkTree{root: &bkNode{word: word}, size: 1}
+	}
+
+	root := t.root.clone()
+	node := root
+	for {
+		distance := levenshtein.Distance(node.word, word)
+		if distance == 0 {
+			return &bkTree{root: root, size: t.size}
+		}
+
+		child, ok := node.children[distance]
+		if !ok {
+			node.children[distance] = &bkNode{word: word}
+			return &bkTree{root: root, size: t.size + 1}
+		}
+		child = child.clone()
+		node.children[distance] = child
+		node = child
+	}
+}
+
+// clone returns a copy of the node that shares its children
+func (n *bkNode) clone() *bkNode {
+	children := make(map[int]*bkNode, len(n.children)+1)
+	for d, child := range n.children {
+		children[d] = child
+	}
+	return &bkNode{word: n.word, children: children}
+}
+
+// Search returns every word of the tree within the given Levenshtein distance of word
+func (t *bkTree) Search(word string, radius int) []string {
+	if t.root == nil {
+		return nil
+	}
+
+	var words []string
+	stack := []*bkNode{t.root}
+	for len(stack) != 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		distance := levenshtein.Distance(node.word, word)
+		if distance <= radius {
+			words = append(words, node.word)
+		}
+
+		// only children between distance-radius and distance+radius can be within radius of word
+		for d, child := range node.children {
+			if d >= distance-radius && d <= distance+radius {
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	return words
+}
+
+// Len returns the number of words on the tree
+func (t *bkTree) Len() int {
+	return t.size
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/Darklabel91/Levenshtein"
+)
+
+var bkWords = []string{"ANA", "ANNA", "ANAH", "HANA", "JOAO", "JOANA", "JOANE", "MARIA", "MARIANA", "MARIANNA", "MARINA", "PEDRO", "PEDRA", "PETRO", "A", ""}
+
+// searchAll returns the words within radius of word, sorted, by comparing every word
+func searchAll(words []string, word string, radius int) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, w := range words {
+		if !seen[w] && levenshtein.Distance(w, word) <= radius {
+			found = append(found, w)
+		}
+		seen[w] = true
+	}
+	sort.Strings(found)
+	return found
+}
+
+func equalWords(a, b []string) bool {
+	sort.Strings(a)
+	sort.Strings(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBKTreeSearch(t *testing.T) {
+	var tree bkTree
+	for _, w := range bkWords {
+		tree.Add(w)
+	}
+	tree.Add("ANA")
+	if tree.Len() != len(bkWords) {
+		t.Fatalf("Len() = %d, want %d", tree.Len(), len(bkWords))
+	}
+
+	for _, word := range []string{"ANA", "MARIA", "PEDRO", "XYZ", ""} {
+		for radius := 0; radius <= 3; radius++ {
+			got := tree.Search(word, radius)
+			want := searchAll(bkWords, word, radius)
+			if !equalWords(got, want) {
+				t.Errorf("Search(%q, %d) = %v, want %v", word, radius, got, want)
+			}
+		}
+	}
+}
+
+func TestBKTreeWith(t *testing.T) {
+	var tree bkTree
+	for _, w := range bkWords[:8] {
+		tree.Add(w)
+	}
+
+	// With copies the tree, the original keeps its words
+	grown := &tree
+	for _, w := range bkWords[8:] {
+		grown = grown.With(w)
+	}
+	if tree.Len() != 8 || grown.Len() != len(bkWords) {
+		t.Fatalf("Len() = %d and %d, want 8 and %d", tree.Len(), grown.Len(), len(bkWords))
+	}
+	if same := grown.With("ANA"); same.Len() != grown.Len() {
+		t.Fatalf("With an existing word has %d words, want %d", same.Len(), grown.Len())
+	}
+
+	for radius := 0; radius <= 3; radius++ {
+		if got, want := tree.Search("MARIANA", radius), searchAll(bkWords[:8], "MARIANA", radius); !equalWords(got, want) {
+			t.Errorf("original Search(MARIANA, %d) = %v, want %v", radius, got, want)
+		}
+		if got, want := grown.Search("MARIANA", radius), searchAll(bkWords, "MARIANA", radius); !equalWords(got, want) {
+			t.Errorf("copy Search(MARIANA, %d) = %v, want %v", radius, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Classifications of a name
+const (
+	ClassificationMale    = "M"
+	ClassificationFemale  = "F"
+	ClassificationUnisex  = "U"
+	ClassificationUnknown = "X"
+)
+
+// classificationAliases maps every accepted spelling of a classification to its value
+var classificationAliases = map[string]string{
+	"M":         ClassificationMale,
+	"MALE":      ClassificationMale,
+	"MASCULINO": ClassificationMale,
+	"F":         ClassificationFemale,
+	"FEMALE":    ClassificationFemale,
+	"FEMININO":  ClassificationFemale,
+	"U":         ClassificationUnisex,
+	"UNISEX":    ClassificationUnisex,
+	"AMBOS":     ClassificationUnisex,
+	"X":         ClassificationUnknown,
+	"UNKNOWN":   ClassificationUnknown,
+	"?":         ClassificationUnknown,
+}
+
+// ParseClassification returns the classification of the given value. It accepts the classification letters and
+// their names in English and Portuguese, in any case. An empty value is unknown.
+func ParseClassification(value string) (string, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return ClassificationUnknown, nil
+	}
+
+	classification, ok := classificationAliases[value]
+	if !ok {
+		return "", fmt.Errorf("invalid classification %q: %w", value, errors.New("classification must be M, F, U or X"))
+	}
+	return classification, nil
+}
+
+// ValidateWeights checks that the frequency weights of the name are not negative
+func (n *NameType) ValidateWeights() error {
+	if n.MaleWeight != nil && *n.MaleWeight < 0 {
+		return errors.New("invalid MaleWeight: weight must not be negative")
+	}
+	if n.FemaleWeight != nil && *n.FemaleWeight < 0 {
+		return errors.New("invalid FemaleWeight: weight must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import "testing"
+
+func TestParseClassification(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"M", ClassificationMale, false},
+		{"m", ClassificationMale, false},
+		{" Male ", ClassificationMale, false},
+		{"masculino", ClassificationMale, false},
+		{"F", ClassificationFemale, false},
+		{"female", ClassificationFemale, false},
+		{"Feminino", ClassificationFemale, false},
+		{"U", ClassificationUnisex, false},
+		{"unisex", ClassificationUnisex, false},
+		{"ambos", ClassificationUnisex, false},
+		{"X", ClassificationUnknown, false},
+		{"unknown", ClassificationUnknown, false},
+		{"?", ClassificationUnknown, false},
+		{"", ClassificationUnknown, false},
+		{"   ", ClassificationUnknown, false},
+		{"MF", "", true},
+		{"homem", "", true},
+		{"N", "", true},
+		{"1", "", true},
+		{"M F", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseClassification(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseClassification(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseClassification(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestValidateWeights(t *testing.T) {
+	weight := func(w float32) *float32 { return &w }
+
+	tests := []struct {
+		name    string
+		male    *float32
+		female  *float32
+		wantErr bool
+	}{
+		{"no weights", nil, nil, false},
+		{"zero weights", weight(0), weight(0), false},
+		{"positive weights", weight(0.8), weight(0.2), false},
+		{"negative male weight", weight(-0.1), weight(1), true},
+		{"negative female weight", nil, weight(-1), true},
+	}
+
+	for _, test := range tests {
+		n := NameType{MaleWeight: test.male, FemaleWeight: test.female}
+		if err := n.ValidateWeights(); (err != nil) != test.wantErr {
+			t.Errorf("%s: ValidateWeights() error = %v, wantErr %v", test.name, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+// cowShards is the number of shards of a cowMap
+const cowShards = 1024
+
+// cowMap is a copy-on-write map split in shards. A clone shares every shard with the original and only copies the
+// shards it writes to, so patching a few entries of a clone doesn't copy the whole map. A map must not be written
+// once it is shared with concurrent readers.
+type cowMap[K comparable, V any] struct {
+	hash   func(K) uint32
+	shards [cowShards]map[K]V
+	owned  [cowShards]bool
+	size   int
+}
+
+// newCowMap returns an empty map that spreads its keys over the shards with the given hash
+func newCowMap[K comparable, V any](hash func(K) uint32) *cowMap[K, V] {
+	m := &cowMap[K, V]{hash: hash}
+	for i := range m.shards {
+		m.shards[i] = make(map[K]V)
+		m.owned[i] = true
+	}
+	return m
+}
+
+// get returns the value of the key
+func (m *cowMap[K, V]) get(key K) (V, bool) {
+	v, ok := m.shards[m.hash(key)%cowShards][key]
+	return v, ok
+}
+
+// set sets the value of the key, copying its shard first if it is shared
+func (m *cowMap[K, V]) set(key K, value V) {
+	shard := m.own(key)
+	if _, ok := shard[key]; !ok {
+		m.size++
+	}
+	shard[key] = value
+}
+
+// remove removes the key, copying its shard first if it is shared
+func (m *cowMap[K, V]) remove(key K) {
+	if _, ok := m.get(key); !ok {
+		return
+	}
+	delete(m.own(key), key)
+	m.size--
+}
+
+// clone returns a copy of the map that shares every shard with m
+func (m *cowMap[K, V]) clone() *cowMap[K, V] {
+	return &cowMap[K, V]{hash: m.hash, shards: m.shards, size: m.size}
+}
+
+// len returns the number of keys of the map
+func (m *cowMap[K, V]) len() int {
+	return m.size
+}
+
+// own returns the shard of the key, copying it first if it is shared
+func (m *cowMap[K, V]) own(key K) map[K]V {
+	i := m.hash(key) % cowShards
+	if !m.owned[i] {
+		shard := make(map[K]V, len(m.shards[i])+1)
+		for k, v := range m.shards[i] {
+			shard[k] = v
+		}
+		m.shards[i] = shard
+		m.owned[i] = true
+	}
+	return m.shards[i]
+}
+
+// hashID spreads sequential ids evenly over the shards
+func hashID(id uint) uint32 {
+	return uint32(id)
+}
+
+// hashString is the 32-bit FNV-1a hash of the string
+func hashString(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
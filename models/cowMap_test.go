@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestCowMapClone(t *testing.T) {
+	m := newCowMap[uint, string](hashID)
+	for id := uint(0); id < 3*cowShards; id++ {
+		m.set(id, "original")
+	}
+
+	// Writes to a clone copy its shards and don't reach the original
+	clone := m.clone()
+	clone.set(1, "changed")
+	clone.set(5*cowShards, "added")
+	clone.remove(2)
+
+	tests := []struct {
+		name  string
+		m     *cowMap[uint, string]
+		key   uint
+		value string
+		ok    bool
+	}{
+		{"original keeps changed key", m, 1, "original", true},
+		{"original lacks added key", m, 5 * cowShards, "", false},
+		{"original keeps removed key", m, 2, "original", true},
+		{"clone has changed key", clone, 1, "changed", true},
+		{"clone has added key", clone, 5 * cowShards, "added", true},
+		{"clone lacks removed key", clone, 2, "", false},
+		{"clone keeps shared key", clone, 3, "original", true},
+		{"clone shares other shards", clone, cowShards + 7, "original", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := tt.m.get(tt.key)
+			if value != tt.value || ok != tt.ok {
+				t.Fatalf("get(%d) = %q, %v, want %q, %v", tt.key, value, ok, tt.value, tt.ok)
+			}
+		})
+	}
+
+	if m.len() != 3*cowShards || clone.len() != 3*cowShards {
+		t.Fatalf("len() = %d and %d, want %d for both", m.len(), clone.len(), 3*cowShards)
+	}
+
+	// Removing a missing key changes nothing
+	clone.remove(2)
+	if clone.len() != 3*cowShards {
+		t.Fatalf("len() after removing a missing key = %d, want %d", clone.len(), 3*cowShards)
+	}
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Darklabel91/Levenshtein"
+	"github.com/Darklabel91/metaphone-br"
+	"github.com/antzucaro/matchr"
+)
+
+// Phonetic algorithms available on the lookup endpoints
+const (
+	AlgorithmMetaphoneBR     = "metaphone-br"
+	AlgorithmSoundex         = "soundex"
+	AlgorithmDoubleMetaphone = "double-metaphone"
+	AlgorithmSpanish         = "spanish"
+)
+
+// DefaultAlgorithm is the phonetic algorithm used when none is chosen
+const DefaultAlgorithm = AlgorithmMetaphoneBR
+
+// Encoder is a phonetic algorithm that encodes names into codes
+type Encoder interface {
+	// Encode returns the phonetic code of a name
+	Encode(name string) string
+	// IsSimilar reports whether two codes of the algorithm are similar
+	IsSimilar(code1, code2 string) bool
+	// MaxDistance returns the Levenshtein distance that bounds every code similar to the given one
+	MaxDistance(code string) int
+}
+
+// Encoders are the available phonetic algorithms by name
+var Encoders = map[string]Encoder{
+	AlgorithmMetaphoneBR:     metaphoneBR{},
+	AlgorithmSoundex:         soundex{},
+	AlgorithmDoubleMetaphone: doubleMetaphone{},
+	AlgorithmSpanish:         spanishKey{},
+}
+
+// GetEncoder returns the encoder of the given algorithm
+func GetEncoder(algorithm string) (Encoder, error) {
+	encoder, ok := Encoders[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown algorithm %q", algorithm)
+	}
+	return encoder, nil
+}
+
+// Code returns the code of the name type stored for the given algorithm
+func (n *NameType) Code(algorithm string) string {
+	switch algorithm {
+	case AlgorithmSoundex:
+		return n.Soundex
+	case AlgorithmDoubleMetaphone:
+		return n.DoubleMetaphone
+	case AlgorithmSpanish:
+		return n.SpanishKey
+	default:
+		return n.Metaphone
+	}
+}
+
+// EncodeName fills every missing phonetic code of the name type
+func (n *NameType) EncodeName() {
+	if n.Metaphone == "" {
+		n.Metaphone = Encoders[AlgorithmMetaphoneBR].Encode(n.Name)
+	}
+	if n.Soundex == "" {
+		n.Soundex = Encoders[AlgorithmSoundex].Encode(n.Name)
+	}
+	if n.DoubleMetaphone == "" {
+		n.DoubleMetaphone = Encoders[AlgorithmDoubleMetaphone].Encode(n.Name)
+	}
+	if n.SpanishKey == "" {
+		n.SpanishKey = Encoders[AlgorithmSpanish].Encode(n.Name)
+	}
+}
+
+// levenshteinMargin is the distance accepted by metaphone.IsMetaphoneSimilar for a code of the given length
+func levenshteinMargin(length int) int {
+	margin := int(metaphone.LevThreshold * float32(length-1))
+	if margin < 1 {
+		margin = 1
+	}
+	return margin
+}
+
+// metaphoneBR is the Brazilian Portuguese metaphone of metaphone-br
+type metaphoneBR struct{}
+
+func (metaphoneBR) Encode(name string) string {
+	return metaphone.Pack(name)
+}
+
+func (metaphoneBR) IsSimilar(code1, code2 string) bool {
+	return metaphone.IsMetaphoneSimilar(code1, code2)
+}
+
+func (metaphoneBR) MaxDistance(code string) int {
+	return levenshteinMargin(len(code))
+}
+
+// soundex is the American Soundex, its codes always have a letter and three digits
+type soundex struct{}
+
+func (soundex) Encode(name string) string {
+	return matchr.Soundex(replaceAccent.Replace(strings.ToUpper(name)))
+}
+
+func (soundex) IsSimilar(code1, code2 string) bool {
+	return code1 != "" && code2 != "" && levenshtein.Distance(code1, code2) <= 1
+}
+
+func (soundex) MaxDistance(string) int {
+	return 1
+}
+
+// doubleMetaphone is the Double Metaphone of Lawrence Philips, only the primary code is stored
+type doubleMetaphone struct{}
+
+func (doubleMetaphone) Encode(name string) string {
+	primary, _ := matchr.DoubleMetaphone(replaceAccent.Replace(strings.ToUpper(name)))
+	return primary
+}
+
+func (doubleMetaphone) IsSimilar(code1, code2 string) bool {
+	return metaphone.IsMetaphoneSimilar(code1, code2)
+}
+
+func (doubleMetaphone) MaxDistance(code string) int {
+	return levenshteinMargin(len(code))
+}
+
+// spanishKey is a phonetic key for Spanish names. It merges the letters that sound alike in Spanish,
+// drops the silent H and the vowels after the first letter and collapses repeated sounds.
+// Y is a vowel unless it is followed by one.
+type spanishKey struct{}
+
+// spanishReplacer replaces the groups of letters of Spanish names by a single sound, longer groups first
+var spanishReplacer = strings.NewReplacer(
+	"CH", "X",
+	"LL", "Y",
+	"QU", "K",
+	"GUE", "GE",
+	"GUI", "GI",
+	"GE", "JE",
+	"GI", "JI",
+	"CE", "SE",
+	"CI", "SI",
+	"C", "K",
+	"Z", "S",
+	"V", "B",
+	"W", "U",
+	"H", "",
+	"X", "KS",
+)
+
+func (spanishKey) Encode(name string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	name = strings.ReplaceAll(name, "Ñ", "NY")
+	name = replaceAccent.Replace(name)
+
+	// keep only letters
+	var letters strings.Builder
+	for _, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			letters.WriteRune(r)
+		}
+	}
+
+	// the replacer doesn't replace its own output, so the X of CH is not replaced by KS
+	replaced := spanishReplacer.Replace(letters.String())
+
+	// drop vowels after the first letter and collapse repeated sounds
+	var key []byte
+	for i := 0; i < len(replaced); i++ {
+		ch := replaced[i]
+		if ch == 'Y' && (i+1 == len(replaced) || !isVowel(replaced[i+1])) {
+			ch = 'I'
+		}
+		if len(key) > 0 && isVowel(ch) {
+			continue
+		}
+		if len(key) > 0 && key[len(key)-1] == ch {
+			continue
+		}
+		key = append(key, ch)
+	}
+
+	return string(key)
+}
+
+func (spanishKey) IsSimilar(code1, code2 string) bool {
+	return metaphone.IsMetaphoneSimilar(code1, code2)
+}
+
+func (spanishKey) MaxDistance(code string) int {
+	return levenshteinMargin(len(code))
+}
+
+// isVowel reports whether the byte is an uppercase vowel
+func isVowel(ch byte) bool {
+	return ch == 'A' || ch == 'E' || ch == 'I' || ch == 'O' || ch == 'U'
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/Darklabel91/Levenshtein"
+)
+
+var encoderNames = []string{
+	"ANA", "ANNA", "HANNA", "JOAO", "JOANA", "JULIANA", "MARIA", "MARIANA", "MARIANNA", "MARINA", "MARINALVA",
+	"PEDRO", "PETRA", "PATRICIA", "CRISTINA", "KRISTINA", "CHRISTIAN", "SEBASTIAO", "XAVIER", "CHAVIER",
+	"GUILHERME", "WILLIAM", "VALERIA", "BARBARA", "FRANCISCO", "FRANCISCA", "GONCALO", "JOSE", "JOSEFA", "ZE",
+	"HELOISA", "ELOISA", "YASMIN", "JASMIN", "THIAGO", "TIAGO", "LETICIA", "LETYCIA", "RAFAEL", "RAPHAEL",
+}
+
+// The searches of similar codes only look within MaxDistance of the code, so every similar code must be within it
+func TestEncoderMaxDistance(t *testing.T) {
+	for algorithm, encoder := range Encoders {
+		t.Run(algorithm, func(t *testing.T) {
+			codes := make([]string, len(encoderNames))
+			for i, name := range encoderNames {
+				codes[i] = encoder.Encode(name)
+			}
+			for _, code := range codes {
+				for _, other := range codes {
+					if !encoder.IsSimilar(code, other) {
+						continue
+					}
+					if d := levenshtein.Distance(code, other); d > encoder.MaxDistance(code) {
+						t.Errorf("%s is similar to %s at distance %d, above MaxDistance %d", other, code, d, encoder.MaxDistance(code))
+					}
+				}
+			}
+		})
+	}
+}
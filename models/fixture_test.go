@@ -0,0 +1,28 @@
+package models
+
+// testNames returns the names the tests resolve against, stored as the seed stores them
+func testNames() []NameType {
+	names := []NameType{
+		{Name: "ANA", Classification: "F", NameVariations: "|AANA|ANA|ANNA|HANA|HANNA|"},
+		{Name: "HELENA", Classification: "F", NameVariations: "|ELENA|HELENA|HELENNA|ILENA|LENA|"},
+		{Name: "JOAO", Classification: "M", NameVariations: "|GOAO|JHOAO|JOAO|"},
+		{Name: "JOSE", Classification: "M", NameVariations: "|GOSE|JHOSE|JOSE|JOZE|"},
+		{Name: "LUIZ", Classification: "M", NameVariations: "|LUIS|LUIZ|LUYS|"},
+		{Name: "MARIA", Classification: "F", NameVariations: "|MARIA|MARIAH|MARYA|"},
+		{Name: "MARIANA", Classification: "F", NameVariations: "|MARIANA|MARIANNA|MARYANA|"},
+		{Name: "PEDRO", Classification: "M", NameVariations: "|PEDRO|PEDROH|"},
+		{Name: "RAFAEL", Classification: "M", NameVariations: "|RAFAEL|RAFEL|RAPHAEL|"},
+		{Name: "SILVA", Classification: "F", NameVariations: "|SILVA|SYLVA|ZILVA|"},
+		{Name: "TIAGO", Classification: "M", NameVariations: "|THIAGO|TIAGO|TYAGO|"},
+	}
+	for i := range names {
+		names[i].ID = uint(i + 1)
+		names[i].EncodeName()
+	}
+	return names
+}
+
+// testIndex returns the index of the test names
+func testIndex() *NameIndex {
+	return NewNameIndex(testNames())
+}
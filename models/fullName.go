@@ -0,0 +1,197 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxBatchSize is the maximum number of full names accepted on a single batch request
+const MaxBatchSize = 10000
+
+// Token kinds found on a full name
+const (
+	TokenGivenName = "given_name"
+	TokenSurname   = "surname"
+	TokenParticle  = "particle"
+)
+
+// Token match status
+const (
+	TokenExact      = "exact"
+	TokenSimilar    = "similar"
+	TokenNotFound   = "not_found"
+	TokenConnective = "connective"
+)
+
+// Full name match status
+const (
+	StatusMatched   = "matched"
+	StatusPartial   = "partial"
+	StatusUnmatched = "unmatched"
+	StatusInvalid   = "invalid"
+)
+
+// Particles are the connective words found between Brazilian given names and surnames
+var Particles = map[string]bool{
+	"DA":  true,
+	"DAS": true,
+	"DE":  true,
+	"DI":  true,
+	"DO":  true,
+	"DOS": true,
+	"E":   true,
+}
+
+// replaceAccent removes the accents of a name so it can be compared with the cached table
+var replaceAccent = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+// BatchInput is the struct for the batch normalization body
+type BatchInput struct {
+	Names []string `json:"Names"`
+}
+
+// TokenMatch is the resolution of a single word of a full name
+type TokenMatch struct {
+	Token          string
+	Kind           string
+	Canonical      string
+	Classification string `json:"Classification,omitempty"`
+	Metaphone      string `json:"Metaphone,omitempty"`
+	Status         string
+}
+
+// FullNameMatch is the resolution of a full name
+type FullNameMatch struct {
+	Input     string
+	Canonical string
+	Tokens    []TokenMatch
+	Status    string
+}
+
+// ResolveFullNames resolves every full name of the slice against the index using the given options.
+// Tokens repeated across the batch are resolved only once.
+func ResolveFullNames(fullNames []string, idx *NameIndex, opts MatchOptions) []FullNameMatch {
+	// Memoize the resolution of every token
+	resolved := make(map[string]TokenMatch)
+
+	var results []FullNameMatch
+	for _, fullName := range fullNames {
+		results = append(results, resolveFullName(fullName, idx, resolved, opts))
+	}
+
+	return results
+}
+
+// resolveFullName splits a full name into given names, surnames and particles and resolves every token
+func resolveFullName(fullName string, idx *NameIndex, resolved map[string]TokenMatch, opts MatchOptions) FullNameMatch {
+	result := FullNameMatch{Input: fullName}
+
+	tokens := splitFullName(fullName)
+	if len(tokens) == 0 {
+		result.Status = StatusInvalid
+		return result
+	}
+
+	var canonical []string
+	var givenNames, matchedGivenNames int
+	for i, token := range tokens {
+		kind := tokenKind(i, tokens, idx)
+
+		var tm TokenMatch
+		if kind == TokenParticle {
+			tm = TokenMatch{Token: token, Canonical: token, Status: TokenConnective}
+		} else {
+			tm = resolveToken(token, kind, idx, resolved, opts)
+		}
+		tm.Kind = kind
+
+		if kind == TokenGivenName {
+			givenNames++
+			if tm.Status != TokenNotFound {
+				matchedGivenNames++
+			}
+		}
+
+		result.Tokens = append(result.Tokens, tm)
+		canonical = append(canonical, tm.Canonical)
+	}
+
+	result.Canonical = strings.Join(canonical, " ")
+
+	switch {
+	case matchedGivenNames == 0:
+		result.Status = StatusUnmatched
+	case matchedGivenNames < givenNames:
+		result.Status = StatusPartial
+	default:
+		result.Status = StatusMatched
+	}
+
+	return result
+}
+
+// resolveToken resolves a single word against the index. Given names fall back to the metaphone match,
+// surnames are only resolved by exact match since the table holds given names.
+func resolveToken(token, kind string, idx *NameIndex, resolved map[string]TokenMatch, opts MatchOptions) TokenMatch {
+	key := kind + ":" + token
+	if tm, ok := resolved[key]; ok {
+		return tm
+	}
+
+	tm := TokenMatch{Token: token, Canonical: token, Status: TokenNotFound}
+	if n, ok := idx.ByName(token); ok {
+		tm.Canonical = n.Name
+		tm.Classification = n.Classification
+		tm.Metaphone = n.Metaphone
+		tm.Status = TokenExact
+	} else if kind == TokenGivenName && len(token) >= 3 {
+		n, err := GetSimilarMatch(token, idx, opts)
+		if err == nil && n.ID != 0 {
+			tm.Canonical = n.Name
+			tm.Classification = n.Classification
+			tm.Metaphone = n.Metaphone
+			tm.Status = TokenSimilar
+		}
+	}
+
+	resolved[key] = tm
+	return tm
+}
+
+// tokenKind classifies the token at index i. The first word is always a given name and the last one a surname,
+// words after a particle are surnames and the remaining ones are given names only if they are on the table.
+func tokenKind(i int, tokens []string, idx *NameIndex) string {
+	if Particles[tokens[i]] && i != 0 && i != len(tokens)-1 {
+		return TokenParticle
+	}
+	if i == 0 {
+		return TokenGivenName
+	}
+	if i == len(tokens)-1 {
+		return TokenSurname
+	}
+	for _, previous := range tokens[1:i] {
+		if Particles[previous] {
+			return TokenSurname
+		}
+	}
+	if _, ok := idx.ByName(tokens[i]); ok {
+		return TokenGivenName
+	}
+	return TokenSurname
+}
+
+// splitFullName uppercases a full name, removes its accents and splits it into words
+func splitFullName(fullName string) []string {
+	fullName = replaceAccent.Replace(strings.ToUpper(fullName))
+	return strings.FieldsFunc(fullName, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitFullName(t *testing.T) {
+	tests := []struct {
+		fullName string
+		want     []string
+	}{
+		{"Maria da Silva", []string{"MARIA", "DA", "SILVA"}},
+		{"  joão   PEDRO  ", []string{"JOAO", "PEDRO"}},
+		{"José Conceição D'Ávila", []string{"JOSE", "CONCEICAO", "D", "AVILA"}},
+		{"Ana-Maria Müller", []string{"ANA", "MARIA", "MULLER"}},
+		{"Ñuño Ïçara", []string{"NUNO", "ICARA"}},
+		{"123 - 456", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitFullName(tt.fullName); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("splitFullName(%q) = %q, want %q", tt.fullName, got, tt.want)
+		}
+	}
+}
+
+func TestResolveFullNames(t *testing.T) {
+	type token struct {
+		canonical string
+		kind      string
+		status    string
+	}
+	tests := []struct {
+		fullName  string
+		canonical string
+		status    string
+		tokens    []token
+	}{
+		{"Maria da Silva", "MARIA DA SILVA", StatusMatched, []token{
+			{"MARIA", TokenGivenName, TokenExact},
+			{"DA", TokenParticle, TokenConnective},
+			{"SILVA", TokenSurname, TokenExact},
+		}},
+		{"JOÃO Pedro dos Santos Pereira", "JOAO PEDRO DOS SANTOS PEREIRA", StatusMatched, []token{
+			{"JOAO", TokenGivenName, TokenExact},
+			{"PEDRO", TokenGivenName, TokenExact},
+			{"DOS", TokenParticle, TokenConnective},
+			{"SANTOS", TokenSurname, TokenNotFound},
+			{"PEREIRA", TokenSurname, TokenNotFound},
+		}},
+		// Names after a particle are surnames, even the ones on the table
+		{"José de Maria Silva", "JOSE DE MARIA SILVA", StatusMatched, []token{
+			{"JOSE", TokenGivenName, TokenExact},
+			{"DE", TokenParticle, TokenConnective},
+			{"MARIA", TokenSurname, TokenExact},
+			{"SILVA", TokenSurname, TokenExact},
+		}},
+		// A word in the middle is a surname when it is not on the table
+		{"Ana Costa e Silva", "ANA COSTA E SILVA", StatusMatched, []token{
+			{"ANA", TokenGivenName, TokenExact},
+			{"COSTA", TokenSurname, TokenNotFound},
+			{"E", TokenParticle, TokenConnective},
+			{"SILVA", TokenSurname, TokenExact},
+		}},
+		// Particles are only connectives between two words, and given names shorter than 3 letters are looked up exactly
+		{"Da Silva", "DA SILVA", StatusUnmatched, []token{
+			{"DA", TokenGivenName, TokenNotFound},
+			{"SILVA", TokenSurname, TokenExact},
+		}},
+		{"Zé Maria dos Anjos", "ZE MARIA DOS ANJOS", StatusPartial, []token{
+			{"ZE", TokenGivenName, TokenNotFound},
+			{"MARIA", TokenGivenName, TokenExact},
+			{"DOS", TokenParticle, TokenConnective},
+			{"ANJOS", TokenSurname, TokenNotFound},
+		}},
+		// Given names not on the table are resolved to the similar ones, surnames only by exact match
+		{"Marianna Helen Souza", "MARIANA HELEN SOUZA", StatusMatched, []token{
+			{"MARIANA", TokenGivenName, TokenSimilar},
+			{"HELEN", TokenSurname, TokenNotFound},
+			{"SOUZA", TokenSurname, TokenNotFound},
+		}},
+		{"Xyzzy da Silva", "XYZZY DA SILVA", StatusUnmatched, []token{
+			{"XYZZY", TokenGivenName, TokenNotFound},
+			{"DA", TokenParticle, TokenConnective},
+			{"SILVA", TokenSurname, TokenExact},
+		}},
+		{"Helena", "HELENA", StatusMatched, []token{
+			{"HELENA", TokenGivenName, TokenExact},
+		}},
+		{"!!!", "", StatusInvalid, nil},
+	}
+
+	fullNames := make([]string, len(tests))
+	for i, tt := range tests {
+		fullNames[i] = tt.fullName
+	}
+	results := ResolveFullNames(fullNames, testIndex(), DefaultMatchOptions())
+	if len(results) != len(tests) {
+		t.Fatalf("ResolveFullNames returned %d results, want %d", len(results), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.fullName, func(t *testing.T) {
+			result := results[i]
+			if result.Input != tt.fullName || result.Canonical != tt.canonical || result.Status != tt.status {
+				t.Fatalf("got %q, canonical %q, status %s, want %q, canonical %q, status %s", result.Input, result.Canonical, result.Status, tt.fullName, tt.canonical, tt.status)
+			}
+			if len(result.Tokens) != len(tt.tokens) {
+				t.Fatalf("got %d tokens, want %d: %+v", len(result.Tokens), len(tt.tokens), result.Tokens)
+			}
+			for j, want := range tt.tokens {
+				got := result.Tokens[j]
+				if got.Canonical != want.canonical || got.Kind != want.kind || got.Status != want.status {
+					t.Errorf("token %d = %s %s %s, want %s %s %s", j, got.Canonical, got.Kind, got.Status, want.canonical, want.kind, want.status)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveFullNamesClassification(t *testing.T) {
+	results := ResolveFullNames([]string{"Rafael Luiz", "Luiz Rafael"}, testIndex(), DefaultMatchOptions())
+	for _, result := range results {
+		for _, tm := range result.Tokens {
+			if tm.Classification != "M" || tm.Metaphone == "" {
+				t.Errorf("%s of %q has classification %q and metaphone %q, want the ones of the table", tm.Token, result.Input, tm.Classification, tm.Metaphone)
+			}
+		}
+	}
+}
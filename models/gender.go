@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Darklabel91/metaphone-br"
+)
+
+// GenderResult is the classification inferred for a given name
+type GenderResult struct {
+	Input          string
+	Name           string `json:"Name,omitempty"`
+	Classification string `json:"Classification,omitempty"`
+	Confidence     float32
+	Similarity     float32
+	Status         string
+}
+
+// GetGender resolves the first name of the input through the metaphone match and returns its classification.
+// The confidence is the similarity between the input and the canonical name, weighted by the share of canonical
+// names reachable through the input, its similar names and the variations of the canonical name that agree with
+// its classification.
+func GetGender(input string, idx *NameIndex, opts MatchOptions) (GenderResult, error) {
+	result := GenderResult{Input: input, Status: TokenNotFound}
+
+	tokens := splitFullName(input)
+	if len(tokens) == 0 {
+		return result, fmt.Errorf("error no name found on %q", input)
+	}
+	name := tokens[0]
+
+	// Resolve the name
+	variations := []string{name}
+	canonical, ok := idx.ByName(name)
+	if ok {
+		result.Status = TokenExact
+	} else {
+		match, err := GetSimilarMatch(name, idx, opts)
+		if err != nil {
+			return result, fmt.Errorf("error getting gender: %w", err)
+		}
+		// GetSimilarMatch overwrites the variations, so get the indexed name type
+		canonical, ok = idx.ByName(match.Name)
+		if !ok {
+			return result, fmt.Errorf("error getting gender: %q is not on the index", match.Name)
+		}
+		result.Status = TokenSimilar
+
+		// The similar names found by the match are variations of the name too
+		for _, v := range strings.Split(match.NameVariations, "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				variations = append(variations, v)
+			}
+		}
+	}
+	variations = append(variations, SplitVariations(canonical.NameVariations)...)
+
+	result.Name = canonical.Name
+	result.Classification = canonical.Classification
+	result.Similarity = metaphone.SimilarityBetweenWords(strings.ToLower(name), strings.ToLower(canonical.Name))
+	result.Confidence = result.Similarity * classificationAgreement(canonical, variations, idx)
+
+	return result, nil
+}
+
+// GetGenders returns the classification of every name of the slice. Names that can't be resolved are returned
+// with the not_found status.
+func GetGenders(inputs []string, idx *NameIndex, opts MatchOptions) []GenderResult {
+	// Memoize the resolution of every name
+	resolved := make(map[string]GenderResult)
+
+	results := make([]GenderResult, 0, len(inputs))
+	for _, input := range inputs {
+		key := strings.Join(splitFullName(input), " ")
+		result, ok := resolved[key]
+		if !ok {
+			result, _ = GetGender(input, idx, opts)
+			resolved[key] = result
+		}
+		result.Input = input
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// classificationAgreement returns the share of canonical names that agree with the classification of canonical.
+// The canonical names considered are canonical itself and every canonical name that is or lists one of the
+// given variations.
+func classificationAgreement(canonical NameType, variations []string, idx *NameIndex) float32 {
+	seen := map[uint]bool{canonical.ID: true}
+	total, agree := 1, 1
+
+	count := func(names []NameType) {
+		for _, n := range names {
+			if seen[n.ID] {
+				continue
+			}
+			seen[n.ID] = true
+			total++
+			if n.Classification == canonical.Classification {
+				agree++
+			}
+		}
+	}
+
+	for _, variation := range variations {
+		if n, ok := idx.ByName(variation); ok {
+			count([]NameType{n})
+		}
+		count(idx.ByVariation(variation))
+	}
+
+	return float32(agree) / float32(total)
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Darklabel91/metaphone-br"
+)
+
+// genderIndex returns an index of the given names, where each name lists its variations
+func genderIndex(names map[string]string, variations map[string]string) *NameIndex {
+	var types []NameType
+	for name, classification := range names {
+		n := NameType{Name: name, Classification: classification, Metaphone: metaphone.Pack(name), NameVariations: variations[name]}
+		n.ID = uint(len(types) + 1)
+		types = append(types, n)
+	}
+	return NewNameIndex(types)
+}
+
+func TestGetGenderConfidence(t *testing.T) {
+	names := map[string]string{
+		// Every name reachable from ANTONIO is masculine
+		"ANTONIO": "M", "TONHO": "M", "TONICO": "M",
+		// Every name reachable from BEATRIZ is feminine
+		"BEATRIZ": "F", "BIA": "F",
+		// Half of the names reachable from ALEX are feminine
+		"ALEX": "M", "ALEXA": "F",
+		// A third of the names reachable from DARCI are masculine
+		"DARCI": "F", "DARCY": "F", "DARSI": "M",
+	}
+	variations := map[string]string{
+		"ANTONIO": "|ANTONIO|TONHO|TONICO|",
+		"TONICO":  "|TONICO|ANTONIO|",
+		"BEATRIZ": "|BEATRIZ|BIA|",
+		"BIA":     "|BIA|BEATRIZ|",
+		"ALEX":    "|ALEX|ALEXA|",
+		"DARCI":   "|DARCI|DARCY|DARSI|",
+	}
+	idx := genderIndex(names, variations)
+
+	tests := []struct {
+		input          string
+		classification string
+		confidence     float64
+	}{
+		{"Antonio", "M", 1},
+		{"antônio da silva", "M", 1},
+		{"Beatriz", "F", 1},
+		{"Bia", "F", 1},
+		{"Alex", "M", 0.5},
+		{"Darci", "F", 2.0 / 3},
+		// The names listed by a variation count too: TONHO doesn't list any, but ANTONIO lists it
+		{"Tonho", "M", 1},
+		// ALEXA doesn't list ALEX, but ALEX lists ALEXA
+		{"Alexa", "F", 0.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := GetGender(test.input, idx, DefaultMatchOptions())
+			if err != nil {
+				t.Fatalf("GetGender(%q) error: %v", test.input, err)
+			}
+			if got.Status != TokenExact || got.Similarity != 1 {
+				t.Fatalf("GetGender(%q) = %+v, want an exact match", test.input, got)
+			}
+			if got.Classification != test.classification {
+				t.Errorf("Classification = %q, want %q", got.Classification, test.classification)
+			}
+			if math.Abs(float64(got.Confidence)-test.confidence) > 1e-6 {
+				t.Errorf("Confidence = %v, want %v", got.Confidence, test.confidence)
+			}
+		})
+	}
+}
+
+func TestGetGenderSimilar(t *testing.T) {
+	got, err := GetGender("Marianna", testIndex(), DefaultMatchOptions())
+	if err != nil {
+		t.Fatalf("GetGender error: %v", err)
+	}
+	if got.Name != "MARIANA" || got.Classification != "F" || got.Status != TokenSimilar {
+		t.Fatalf("GetGender = %+v, want MARIANA F similar", got)
+	}
+
+	// An agreement of 1 leaves the confidence at the similarity
+	similarity := metaphone.SimilarityBetweenWords("marianna", "mariana")
+	if got.Similarity != similarity || got.Confidence != similarity {
+		t.Errorf("Similarity = %v, Confidence = %v, want both %v", got.Similarity, got.Confidence, similarity)
+	}
+	if got.Confidence <= 0 || got.Confidence >= 1 {
+		t.Errorf("Confidence = %v, want between 0 and 1", got.Confidence)
+	}
+}
+
+func TestGetGenders(t *testing.T) {
+	inputs := []string{"Maria", "maria", "Xyzzy", "", "Pedro Silva"}
+	got := GetGenders(inputs, testIndex(), DefaultMatchOptions())
+	if len(got) != len(inputs) {
+		t.Fatalf("GetGenders returned %d results, want %d", len(got), len(inputs))
+	}
+
+	want := []struct{ name, classification, status string }{
+		{"MARIA", "F", TokenExact},
+		{"MARIA", "F", TokenExact},
+		{"", "", TokenNotFound},
+		{"", "", TokenNotFound},
+		{"PEDRO", "M", TokenExact},
+	}
+	for i, w := range want {
+		if got[i].Input != inputs[i] {
+			t.Errorf("result %d Input = %q, want %q", i, got[i].Input, inputs[i])
+		}
+		if got[i].Name != w.name || got[i].Classification != w.classification || got[i].Status != w.status {
+			t.Errorf("GetGenders(%q) = %+v, want %s %s %s", inputs[i], got[i], w.name, w.classification, w.status)
+		}
+		if w.status == TokenNotFound && got[i].Confidence != 0 {
+			t.Errorf("GetGenders(%q) Confidence = %v, want 0", inputs[i], got[i].Confidence)
+		}
+	}
+}
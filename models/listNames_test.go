@@ -0,0 +1,162 @@
+package models_test
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+// listAll follows the cursors of the listing to its last page and returns the names of every page
+func listAll(t *testing.T, opts models.ListOptions) []string {
+	t.Helper()
+	var names []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("listing didn't end after 10 pages")
+		}
+		page, err := models.ListNames(opts)
+		if err != nil {
+			t.Fatalf("ListNames(%+v) error: %v", opts, err)
+		}
+		if len(page.Names) > opts.Limit {
+			t.Fatalf("page of %d names, want at most %d", len(page.Names), opts.Limit)
+		}
+		for _, n := range page.Names {
+			names = append(names, n.Name)
+		}
+		if page.NextCursor == "" {
+			return names
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+func TestListNamesPages(t *testing.T) {
+	db := dbtest.Open(t)
+	names := []models.NameType{
+		{Name: "MARIA", Classification: models.ClassificationFemale},
+		{Name: "ANA", Classification: models.ClassificationFemale},
+		{Name: "PEDRO", Classification: models.ClassificationMale},
+		{Name: "ALEX", Classification: models.ClassificationUnisex},
+		{Name: "JOAO", Classification: models.ClassificationMale},
+		{Name: "BEATRIZ", Classification: models.ClassificationFemale},
+		{Name: "CARLOS", Classification: models.ClassificationMale},
+	}
+	if err := db.Create(&names).Error; err != nil {
+		t.Fatalf("error creating names: %v", err)
+	}
+
+	// Names created at the same time are ordered by their ids
+	created := time.Date(2023, 4, 12, 18, 0, 0, 0, time.UTC)
+	for i := range names {
+		names[i].CreatedAt = created.Add(time.Duration(i/3) * time.Hour)
+		if err := db.Model(&names[i]).UpdateColumn("created_at", names[i].CreatedAt).Error; err != nil {
+			t.Fatalf("error updating name: %v", err)
+		}
+	}
+
+	// ordered returns the names sorted by the key and then by id
+	ordered := func(less func(a, b models.NameType) bool, desc bool) []string {
+		sorted := append([]models.NameType(nil), names...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if desc {
+				a, b = b, a
+			}
+			if less(a, b) {
+				return true
+			}
+			return !less(b, a) && a.ID < b.ID
+		})
+		out := make([]string, 0, len(sorted))
+		for _, n := range sorted {
+			out = append(out, n.Name)
+		}
+		return out
+	}
+	byID := func(a, b models.NameType) bool { return a.ID < b.ID }
+	byName := func(a, b models.NameType) bool { return a.Name < b.Name }
+	byCreated := func(a, b models.NameType) bool { return a.CreatedAt.Before(b.CreatedAt) }
+
+	tests := []struct {
+		sort string
+		desc bool
+		want []string
+	}{
+		{"", false, ordered(byID, false)},
+		{"id", true, ordered(byID, true)},
+		{"name", false, ordered(byName, false)},
+		{"name", true, ordered(byName, true)},
+		{"created_at", false, ordered(byCreated, false)},
+		{"created_at", true, ordered(byCreated, true)},
+	}
+	for _, tt := range tests {
+		for _, limit := range []int{1, 3, 7} {
+			got := listAll(t, models.ListOptions{Sort: tt.sort, Desc: tt.desc, Limit: limit})
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("sort %q desc %v limit %d listed %v, want %v", tt.sort, tt.desc, limit, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestListNamesFilters(t *testing.T) {
+	dbtest.Open(t)
+	names := []models.NameType{
+		{Name: "MARIA", Classification: models.ClassificationFemale},
+		{Name: "MARCOS", Classification: models.ClassificationMale},
+		{Name: "ANA", Classification: models.ClassificationFemale},
+		{Name: "MARTA", Classification: models.ClassificationFemale},
+	}
+	for i := range names {
+		if err := names[i].CreateName(); err != nil {
+			t.Fatalf("error creating name: %v", err)
+		}
+	}
+	if err := names[3].DeleteName(); err != nil {
+		t.Fatalf("error deleting name: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts models.ListOptions
+		want []string
+	}{
+		{"classification", models.ListOptions{Classification: models.ClassificationFemale}, []string{"MARIA", "ANA"}},
+		{"prefix", models.ListOptions{Prefix: "MAR"}, []string{"MARIA", "MARCOS"}},
+		{"metaphone", models.ListOptions{Metaphone: names[2].Metaphone}, []string{"ANA"}},
+		{"deleted", models.ListOptions{Prefix: "MAR", IncludeDeleted: true}, []string{"MARIA", "MARCOS", "MARTA"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Limit = 1
+			page, err := models.ListNames(tt.opts)
+			if err != nil {
+				t.Fatalf("ListNames() error: %v", err)
+			}
+			if page.Total != int64(len(tt.want)) {
+				t.Errorf("Total = %d, want %d", page.Total, len(tt.want))
+			}
+			if got := listAll(t, tt.opts); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("listed %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A cursor belongs to the sort of its listing
+	page, err := models.ListNames(models.ListOptions{Sort: "name", Limit: 1})
+	if err != nil {
+		t.Fatalf("ListNames() error: %v", err)
+	}
+	if _, err = models.ListNames(models.ListOptions{Sort: "id", Limit: 1, Cursor: page.NextCursor}); !errors.Is(err, models.ErrInvalidCursor) {
+		t.Fatalf("cursor of another sort error = %v, want ErrInvalidCursor", err)
+	}
+	if _, err = models.ListNames(models.ListOptions{Sort: "classification"}); err == nil {
+		t.Fatal("ListNames() with an invalid sort succeeded, want an error")
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultMaxRelaxation is how much the similarity threshold is lowered when nothing is found with the requested one
+const DefaultMaxRelaxation = 0.1
+
+// MatchOptions controls the thresholds and fallbacks of the metaphone resolution pipeline
+type MatchOptions struct {
+	// Threshold is the minimum similarity between two names for them to be considered similar
+	Threshold float32
+	// Fallback enables the similar metaphone search, the similar names expansion and the relaxed threshold retries
+	Fallback bool
+	// MaxRelaxation is how much Threshold is lowered on the relaxed threshold retries
+	MaxRelaxation float32
+	// Algorithm is the phonetic algorithm used to find names that sound alike, see Encoders
+	Algorithm string
+}
+
+// DefaultMatchOptions returns the options used when neither the request nor the API user sets them
+func DefaultMatchOptions() MatchOptions {
+	return MatchOptions{
+		Threshold:     SimilarityThreshold,
+		Fallback:      true,
+		MaxRelaxation: DefaultMaxRelaxation,
+		Algorithm:     DefaultAlgorithm,
+	}
+}
+
+// Validate checks that the thresholds of the options are within range and that the algorithm exists
+func (o MatchOptions) Validate() error {
+	if _, err := GetEncoder(o.Algorithm); err != nil {
+		return fmt.Errorf("invalid algorithm: %w", err)
+	}
+	// NaN fails no comparison, so it is rejected first
+	if !isFinite(o.Threshold) || o.Threshold <= 0 || o.Threshold > 1 {
+		return fmt.Errorf("invalid threshold: %w", errors.New("threshold must be greater than 0 and at most 1"))
+	}
+	if !isFinite(o.MaxRelaxation) || o.MaxRelaxation < 0 || o.MaxRelaxation >= o.Threshold {
+		return fmt.Errorf("invalid maxRelaxation: %w", errors.New("maxRelaxation must be at least 0 and lower than threshold"))
+	}
+	return nil
+}
+
+// isFinite reports whether f is neither NaN nor infinite
+func isFinite(f float32) bool {
+	return !math.IsNaN(float64(f)) && !math.IsInf(float64(f), 0)
+}
+
+// encode returns the code of the name on the algorithm of the options
+func (o MatchOptions) encode(name string) string {
+	encoder, err := GetEncoder(o.Algorithm)
+	if err != nil {
+		encoder = Encoders[DefaultAlgorithm]
+	}
+	return encoder.Encode(name)
+}
+
+// relaxedThreshold returns the threshold used on the relaxed retries
+func (o MatchOptions) relaxedThreshold() float32 {
+	return o.Threshold - o.MaxRelaxation
+}
+
+// relax reports whether the relaxed threshold retries must run
+func (o MatchOptions) relax() bool {
+	return o.Fallback && o.MaxRelaxation > 0
+}
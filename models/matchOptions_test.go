@@ -0,0 +1,45 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMatchOptionsValidate(t *testing.T) {
+	nan := float32(math.NaN())
+	inf := float32(math.Inf(1))
+	tests := []struct {
+		name          string
+		threshold     float32
+		maxRelaxation float32
+		algorithm     string
+		valid         bool
+	}{
+		{"defaults", 0.8, 0.1, DefaultAlgorithm, true},
+		{"threshold 1", 1, 0, DefaultAlgorithm, true},
+		{"threshold 0", 0, 0, DefaultAlgorithm, false},
+		{"threshold above 1", 1.1, 0, DefaultAlgorithm, false},
+		{"threshold NaN", nan, 0.1, DefaultAlgorithm, false},
+		{"threshold infinite", inf, 0.1, DefaultAlgorithm, false},
+		{"negative maxRelaxation", 0.8, -0.1, DefaultAlgorithm, false},
+		{"maxRelaxation equal to threshold", 0.8, 0.8, DefaultAlgorithm, false},
+		{"maxRelaxation NaN", 0.8, nan, DefaultAlgorithm, false},
+		{"maxRelaxation infinite", 0.8, -inf, DefaultAlgorithm, false},
+		{"soundex", 0.8, 0.1, AlgorithmSoundex, true},
+		{"double metaphone", 0.8, 0.1, AlgorithmDoubleMetaphone, true},
+		{"spanish", 0.8, 0.1, AlgorithmSpanish, true},
+		{"unknown algorithm", 0.8, 0.1, "nysiis", false},
+		{"empty algorithm", 0.8, 0.1, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultMatchOptions()
+			opts.Threshold = tt.threshold
+			opts.MaxRelaxation = tt.maxRelaxation
+			opts.Algorithm = tt.algorithm
+			if err := opts.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
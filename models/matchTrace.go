@@ -0,0 +1,53 @@
+package models
+
+// Stages of the metaphone resolution pipeline
+const (
+	StageExactMatch                = "exact_match"
+	StageExactMetaphone            = "exact_metaphone"
+	StageSimilarMetaphone          = "similar_metaphone"
+	StageSimilarNames              = "similar_names"
+	StageSimilarNamesRelaxed       = "similar_names_relaxed"
+	StageSimilarNamesExpansion     = "similar_names_expansion"
+	StageOrderBySimilarity         = "order_by_similarity"
+	StageCanonicalExactMetaphone   = "canonical_exact_metaphone"
+	StageCanonicalSimilarMetaphone = "canonical_similar_metaphone"
+	StageCanonicalExactVariation   = "canonical_exact_variation"
+	StageCanonicalSimilarVariation = "canonical_similar_variation"
+	StageCanonicalRelaxedVariation = "canonical_relaxed_variation"
+)
+
+// TraceStage is a single stage entered by the metaphone resolution pipeline
+type TraceStage struct {
+	Stage      string
+	Candidates int
+	Threshold  float32 `json:"Threshold,omitempty"`
+	Matched    bool
+	Detail     string `json:"Detail,omitempty"`
+}
+
+// MatchTrace records every stage entered while resolving a name and the reason the winner was chosen
+type MatchTrace struct {
+	Input     string
+	Metaphone string // code of the input on the phonetic algorithm of Options
+	Options   MatchOptions
+	Stages    []TraceStage
+	Winner    string `json:"Winner,omitempty"`
+	Reason    string `json:"Reason,omitempty"`
+}
+
+// addStage appends a stage to the trace. It is safe to call on a nil trace so the pipeline doesn't need to check it.
+func (t *MatchTrace) addStage(stage TraceStage) {
+	if t == nil {
+		return
+	}
+	t.Stages = append(t.Stages, stage)
+}
+
+// setWinner records the chosen name and the reason it was chosen. It is safe to call on a nil trace.
+func (t *MatchTrace) setWinner(name, reason string) {
+	if t == nil {
+		return
+	}
+	t.Winner = name
+	t.Reason = reason
+}
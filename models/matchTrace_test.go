@@ -0,0 +1,153 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Darklabel91/metaphone-br"
+)
+
+func TestMatchTraceNil(t *testing.T) {
+	var trace *MatchTrace
+	trace.addStage(TraceStage{Stage: StageExactMatch})
+	trace.setWinner("ANA", "exact name found on the database")
+}
+
+// traceCanonicalName runs the pipeline on the test names with the given options, recording its stages on a trace
+func traceCanonicalName(name string, opts MatchOptions) (*NameType, *MatchTrace, error) {
+	return GetSimilarMatchTrace(name, testIndex(), opts)
+}
+
+var reasonSimilarity = regexp.MustCompile(`similarity (\d\.\d\d)`)
+
+func TestSearchCanonicalNameTrace(t *testing.T) {
+	tests := []struct {
+		name   string
+		winner string
+		stage  string
+	}{
+		{"Mariana", "MARIANA", StageExactMatch},
+		{"Marianna", "MARIANA", StageCanonicalSimilarVariation},
+		{"Helen", "HELENA", StageCanonicalSimilarVariation},
+		{"Rafel", "RAFAEL", StageCanonicalSimilarVariation},
+		{"Luis", "LUIZ", StageCanonicalRelaxedVariation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, trace, err := traceCanonicalName(tt.name, DefaultMatchOptions())
+			if err != nil {
+				t.Fatalf("error finding canonical name: %v", err)
+			}
+			if n.Name != tt.winner || trace.Winner != n.Name {
+				t.Fatalf("canonical name %s with trace winner %s, want %s", n.Name, trace.Winner, tt.winner)
+			}
+
+			// The winner is chosen by the last stage, the only matched canonical stage
+			last := trace.Stages[len(trace.Stages)-1]
+			if last.Stage != tt.stage || !last.Matched {
+				t.Fatalf("last stage = %+v, want %s matched", last, tt.stage)
+			}
+			for _, stage := range trace.Stages[:len(trace.Stages)-1] {
+				if strings.HasPrefix(stage.Stage, "canonical_") && stage.Matched {
+					t.Errorf("stage %s matched before the winner", stage.Stage)
+				}
+			}
+
+			// The similarity on the reason is the score of the winner, at least the threshold of its stage
+			if m := reasonSimilarity.FindStringSubmatch(trace.Reason); m != nil {
+				similarity := metaphone.SimilarityBetweenWords(strings.ToUpper(tt.name), n.Name)
+				if want := fmt.Sprintf("%.2f", similarity); m[1] != want {
+					t.Errorf("reason %q has similarity %s, want %s", trace.Reason, m[1], want)
+				}
+				if similarity < last.Threshold {
+					t.Errorf("winner similarity %v is below the threshold %v of its stage", similarity, last.Threshold)
+				}
+			} else if tt.stage != StageExactMatch {
+				t.Errorf("reason %q has no similarity", trace.Reason)
+			}
+		})
+	}
+}
+
+func TestGetSimilarMatchTrace(t *testing.T) {
+	// The trace doesn't change the result of the pipeline
+	for _, name := range []string{"Mariana", "Marianna", "Helen", "Luis", "Thiago", "Pedru", "Xyzzy", "Ane"} {
+		opts := DefaultMatchOptions()
+		want, wantErr := GetSimilarMatch(name, testIndex(), opts)
+		got, trace, err := GetSimilarMatchTrace(name, testIndex(), opts)
+		if (err == nil) != (wantErr == nil) {
+			t.Fatalf("%s: GetSimilarMatchTrace error %v, GetSimilarMatch error %v", name, err, wantErr)
+		}
+		if trace.Input != name || trace.Metaphone != metaphone.Pack(name) || trace.Options != opts {
+			t.Errorf("%s: trace of %q, metaphone %q and options %+v, want the ones of the request", name, trace.Input, trace.Metaphone, trace.Options)
+		}
+		if err != nil {
+			if trace.Winner != "" {
+				t.Errorf("%s: trace winner %s without a match", name, trace.Winner)
+			}
+			continue
+		}
+		if got.Name != want.Name || trace.Winner != got.Name {
+			t.Errorf("%s: GetSimilarMatchTrace = %s with winner %s, GetSimilarMatch = %s", name, got.Name, trace.Winner, want.Name)
+		}
+	}
+}
+
+func TestSearchSimilarNamesTrace(t *testing.T) {
+	for _, name := range []string{"Marianna", "Luis", "Xyzzy"} {
+		trace := &MatchTrace{Input: name}
+		similarNames := SearchSimilarNames(name, testNames(), DefaultMatchOptions(), trace)
+
+		// The stage reports the names it found, with the threshold they were found with
+		last := trace.Stages[len(trace.Stages)-1]
+		if last.Candidates != len(similarNames) || last.Matched != (len(similarNames) != 0) {
+			t.Errorf("%s: last stage %+v, want %d candidates", name, last, len(similarNames))
+		}
+		for _, sn := range similarNames {
+			if sn.Similarity < last.Threshold {
+				t.Errorf("%s: %s has similarity %v, below the threshold %v of the stage", name, sn.Name, sn.Similarity, last.Threshold)
+			}
+		}
+	}
+
+	// Without a match on the threshold the relaxed stage runs
+	opts := DefaultMatchOptions()
+	trace := &MatchTrace{}
+	SearchSimilarNames("Luis", testNames(), opts, trace)
+	if len(trace.Stages) != 2 || trace.Stages[0].Threshold != opts.Threshold || trace.Stages[1].Stage != StageSimilarNamesRelaxed || trace.Stages[1].Threshold != opts.relaxedThreshold() {
+		t.Fatalf("stages = %+v, want similar_names and then similar_names_relaxed on the relaxed threshold", trace.Stages)
+	}
+
+	// Without fallback it doesn't
+	opts.Fallback = false
+	trace = &MatchTrace{}
+	if similarNames := SearchSimilarNames("Luis", testNames(), opts, trace); len(similarNames) != 0 || len(trace.Stages) != 1 {
+		t.Fatalf("SearchSimilarNames without fallback = %v with stages %+v, want no names on a single stage", similarNames, trace.Stages)
+	}
+}
+
+func TestSearchCanonicalNameOptions(t *testing.T) {
+	// The relaxed stage finds LUIZ only when fallback is enabled
+	opts := DefaultMatchOptions()
+	opts.Fallback = false
+	if n, trace, err := traceCanonicalName("Luis", opts); err == nil {
+		t.Fatalf("canonical name without fallback = %s, want an error", n.Name)
+	} else if trace.Winner != "" {
+		t.Fatalf("trace winner without fallback = %s, want none", trace.Winner)
+	}
+
+	// A stricter threshold needs a larger relaxation to find it
+	opts = DefaultMatchOptions()
+	opts.Threshold, opts.MaxRelaxation = 0.95, 0.1
+	if _, _, err := traceCanonicalName("Luis", opts); err == nil {
+		t.Fatal("canonical name found with threshold 0.95 relaxed by 0.1, want an error")
+	}
+	opts.MaxRelaxation = 0.3
+	if n, trace, err := traceCanonicalName("Luis", opts); err != nil || n.Name != "LUIZ" {
+		t.Fatalf("canonical name with threshold 0.95 relaxed by 0.3 = %v, %v, want LUIZ", n, err)
+	} else if last := trace.Stages[len(trace.Stages)-1]; last.Threshold != opts.relaxedThreshold() {
+		t.Fatalf("last stage threshold = %v, want the relaxed threshold %v", last.Threshold, opts.relaxedThreshold())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/Darklabel91/metaphone-br"
 	"gorm.io/gorm"
+	"sort"
 	"strings"
 )
 
@@ -18,26 +19,55 @@ type NameType struct {
 	Classification string `json:"Classification,omitempty"`
 	Metaphone      string `gorm:"index" json:"Metaphone,omitempty"`
 	NameVariations string `json:"NameVariations,omitempty"`
+
+	// Codes of the other phonetic algorithms, see Encoders
+	Soundex         string `gorm:"size:16;index" json:"Soundex,omitempty"`
+	DoubleMetaphone string `gorm:"size:64;index" json:"DoubleMetaphone,omitempty"`
+	SpanishKey      string `gorm:"size:64;index" json:"SpanishKey,omitempty"`
+
+	// Optional frequency weights of the name among males and females
+	MaleWeight   *float32 `json:"MaleWeight,omitempty"`
+	FemaleWeight *float32 `json:"FemaleWeight,omitempty"`
+
+	// Variations are the rows of the name_variations table, NameVariations is kept in sync with them. DeleteName
+	// deletes them, as the cascade only runs on hard deletes.
+	Variations []NameVariation `gorm:"constraint:OnDelete:CASCADE" json:"-"`
 }
 
-// CreateName creates a new name record
+// CreateName creates a new name record and its variations. Names without classification are created as unknown
+// and missing phonetic codes are encoded from the name.
 func (n *NameType) CreateName() error {
-	err := DB.Create(&n)
-	if err.Error != nil {
-		return fmt.Errorf("error creating name: %w", err.Error)
+	if n.Classification == "" {
+		n.Classification = ClassificationUnknown
+	}
+	n.EncodeName()
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&n).Error; err != nil {
+			return err
+		}
+		if err := n.ReplaceVariations(tx, SourceAPI); err != nil {
+			return err
+		}
+		return recordChange(tx, n.ID, ChangeUpsert)
+	})
+	if err != nil {
+		return fmt.Errorf("error creating name: %w", err)
 	}
 	return nil
 }
 
-// UpdateName updates a name from the database by its ID.
-func (n *NameType) UpdateName(db *gorm.DB, updateName NameType) (NameType, error) {
+// UpdateName updates a name from the database by its ID. Changing NameVariations replaces all variations of the name.
+func (n *NameType) UpdateName(updateName NameType) (NameType, error) {
 	// Check if input is the same as the name in the database
-	if updateName.Name == n.Name && updateName.Classification == n.Classification && updateName.Metaphone == n.Metaphone && updateName.NameVariations == n.NameVariations {
+	if updateName.Name == n.Name && updateName.Classification == n.Classification && updateName.Metaphone == n.Metaphone && updateName.NameVariations == n.NameVariations && updateName.MaleWeight == nil && updateName.FemaleWeight == nil {
 		return NameType{}, fmt.Errorf("no update detected: %w", errors.New("update struct is exactly the same of original struct"))
 	} else {
 		// Update the name properties if they have changed
 		if updateName.Name != "" && updateName.Name != n.Name {
 			n.Name = updateName.Name
+			// Encode the new name on every algorithm
+			n.Metaphone, n.Soundex, n.DoubleMetaphone, n.SpanishKey = "", "", "", ""
 		}
 		if updateName.Classification != "" && updateName.Classification != n.Classification {
 			n.Classification = updateName.Classification
@@ -45,21 +75,39 @@ func (n *NameType) UpdateName(db *gorm.DB, updateName NameType) (NameType, error
 		if updateName.Metaphone != "" && updateName.Metaphone != n.Metaphone {
 			n.Metaphone = updateName.Metaphone
 		}
-		if updateName.NameVariations != "" && updateName.NameVariations != n.NameVariations {
-			n.NameVariations = updateName.NameVariations
+		if updateName.MaleWeight != nil {
+			n.MaleWeight = updateName.MaleWeight
+		}
+		if updateName.FemaleWeight != nil {
+			n.FemaleWeight = updateName.FemaleWeight
 		}
+		n.EncodeName()
+	}
+	variationsChanged := updateName.NameVariations != "" && updateName.NameVariations != n.NameVariations
+	if variationsChanged {
+		n.NameVariations = updateName.NameVariations
 	}
 
-	// Save the updated name to the database
-	err := db.Save(&n).Error
+	// Save the updated name and its variations to the database
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&n).Error; err != nil {
+			return err
+		}
+		if variationsChanged {
+			if err := n.ReplaceVariations(tx, SourceAPI); err != nil {
+				return err
+			}
+		}
+		return recordChange(tx, n.ID, ChangeUpsert)
+	})
 	if err != nil {
-		return NameType{}, fmt.Errorf("error on updating item: %w", err.Error)
+		return NameType{}, fmt.Errorf("error on updating item: %w", err)
 	}
 
 	return *n, nil
 }
 
-// DeleteName deletes a name from the database by its ID.
+// DeleteName deletes a name from the database by its ID, with its variations.
 func (n *NameType) DeleteName() error {
 	err := DB.Where("id = ?", n.ID)
 	if err.Error != nil {
@@ -70,14 +118,34 @@ func (n *NameType) DeleteName() error {
 		return fmt.Errorf("name already deleted")
 	}
 
-	DB.Delete(&n)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&n).Error; err != nil {
+			return fmt.Errorf("error deleting name: %w", err)
+		}
+		// The delete is soft, so the foreign key cascade doesn't run
+		if err := tx.Where("name_type_id = ?", n.ID).Delete(&NameVariation{}).Error; err != nil {
+			return fmt.Errorf("error deleting name variations: %w", err)
+		}
+		return recordChange(tx, n.ID, ChangeDelete)
+	})
+}
+
+// PingDB checks that the database is reachable
+func PingDB() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("error getting database connection: %w", err)
+	}
+	if err = sqlDB.Ping(); err != nil {
+		return fmt.Errorf("error pinging database: %w", err)
+	}
 	return nil
 }
 
 // GetAllNames returns all non-deleted names in the database
 func GetAllNames() ([]NameType, error) {
 	var Names []NameType
-	err := DB.Raw("SELECT * FROM name_types").Find(&Names)
+	err := DB.Find(&Names)
 	if err.Error != nil {
 		return nil, fmt.Errorf("error getting all names:  %w", err.Error)
 	}
@@ -87,7 +155,7 @@ func GetAllNames() ([]NameType, error) {
 // GetNameById returns the name record with the given ID (non-deleted)
 func GetNameById(id int) (*NameType, *gorm.DB, error) {
 	var getName NameType
-	data := DB.Raw("SELECT * FROM name_types WHERE id = ?", id).Find(&getName)
+	data := DB.Where("id = ?", id).Limit(1).Find(&getName)
 	if data.Error != nil {
 		return nil, nil, fmt.Errorf("error getting name by id:  %w", data.Error)
 	}
@@ -101,49 +169,71 @@ func GetNameById(id int) (*NameType, *gorm.DB, error) {
 // GetNameByName returns the name record with the given name (non-deleted)
 func GetNameByName(name string) (*NameType, error) {
 	var getName NameType
-	data := DB.Raw("SELECT * FROM name_types WHERE name = ?", name).Find(&getName)
+	data := DB.Where("name = ?", name).Limit(1).Find(&getName)
 	if data.Error != nil {
 		return nil, fmt.Errorf("error getting name by name:  %w", data.Error)
 	}
 	return &getName, nil
 }
 
-// GetSimilarMatch searches for a similar match for a given name in a slice of NameType.
-func GetSimilarMatch(name string, allNames []NameType) (*NameType, error) {
-	// Search for an exact match in the database.
-	perfectMatch, err := GetNameByName(strings.ToUpper(name))
-	if err != nil {
-		return nil, fmt.Errorf("error getting name by similar match: %w", err)
-	}
-	if perfectMatch.ID != 0 {
-		return perfectMatch, nil
+// GetSimilarMatch searches for a similar match for a given name on the index using the given options.
+func GetSimilarMatch(name string, idx *NameIndex, opts MatchOptions) (*NameType, error) {
+	return getSimilarMatch(name, idx, opts, nil)
+}
+
+// GetSimilarMatchTrace searches for a similar match for a given name on the index and returns the trace of
+// every stage entered by the pipeline. The trace is returned even if no match is found.
+func GetSimilarMatchTrace(name string, idx *NameIndex, opts MatchOptions) (*NameType, *MatchTrace, error) {
+	trace := &MatchTrace{Input: name, Metaphone: opts.encode(name), Options: opts}
+	canonicalEntity, err := getSimilarMatch(name, idx, opts, trace)
+	return canonicalEntity, trace, err
+}
+
+// getSimilarMatch runs the metaphone resolution pipeline recording its stages on trace, which may be nil.
+func getSimilarMatch(name string, idx *NameIndex, opts MatchOptions, trace *MatchTrace) (*NameType, error) {
+	// Search for an exact match on the index.
+	if perfectMatch, ok := idx.ByName(strings.ToUpper(name)); ok {
+		trace.addStage(TraceStage{Stage: StageExactMatch, Candidates: 1, Matched: true})
+		trace.setWinner(perfectMatch.Name, "exact name found on the index")
+		return &perfectMatch, nil
 	}
+	trace.addStage(TraceStage{Stage: StageExactMatch})
 
 	// Search for a similar match.
-	nameMetaphone := metaphone.Pack(name)
+	nameMetaphone := opts.encode(name)
 
 	// Search for the exact metaphone match.
-	exactMetaphoneMatches := SearchCacheMetaphone(nameMetaphone, allNames)
-	if len(exactMetaphoneMatches) == 0 {
+	exactMetaphoneMatches := SearchCacheMetaphone(nameMetaphone, opts.Algorithm, idx)
+	trace.addStage(TraceStage{Stage: StageExactMetaphone, Candidates: len(exactMetaphoneMatches), Matched: len(exactMetaphoneMatches) != 0})
+	if len(exactMetaphoneMatches) == 0 && opts.Fallback {
 		// Search for all similar metaphone codes if no exact match is found.
-		exactMetaphoneMatches = SearchSimilarMetaphone(nameMetaphone, allNames)
-		if len(exactMetaphoneMatches) == 0 {
-			return nil, fmt.Errorf("error no matches found for name: %w", err)
-		}
+		exactMetaphoneMatches = SearchSimilarMetaphone(nameMetaphone, opts.Algorithm, idx)
+		trace.addStage(TraceStage{Stage: StageSimilarMetaphone, Candidates: len(exactMetaphoneMatches), Matched: len(exactMetaphoneMatches) != 0})
+	}
+	if len(exactMetaphoneMatches) == 0 {
+		return nil, fmt.Errorf("error no matches found for name %q", name)
 	}
 
 	// Get all similar names by metaphone list.
-	similarNames := SearchSimilarNames(name, exactMetaphoneMatches, SimilarityThreshold)
+	similarNames := SearchSimilarNames(name, exactMetaphoneMatches, opts, trace)
 	if len(similarNames) == 0 {
 		return nil, fmt.Errorf("error no similar names found for %q", name)
 	}
 
 	// Search for all similar names of all similar names listed so far if similarNames is too small.
-	if len(similarNames) < 5 {
+	if len(similarNames) < 5 && opts.Fallback {
+		before := len(similarNames)
 		for _, sn := range similarNames {
-			similar := SearchSimilarNames(sn.Name, exactMetaphoneMatches, SimilarityThreshold)
+			similar := SearchSimilarNames(sn.Name, exactMetaphoneMatches, opts, nil)
 			similarNames = append(similarNames, similar...)
 		}
+		trace.addStage(TraceStage{
+			Stage:      StageSimilarNamesExpansion,
+			Candidates: len(similarNames),
+			Threshold:  opts.Threshold,
+			Matched:    len(similarNames) > before,
+			Detail:     fmt.Sprintf("expanded %d similar names because fewer than 5 were found", before),
+		})
 	}
 
 	// Order all similarNames by LEVENSHTEIN from high to low.
@@ -151,9 +241,10 @@ func GetSimilarMatch(name string, allNames []NameType) (*NameType, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error failed to order by similar names: %w", err)
 	}
+	trace.addStage(TraceStage{Stage: StageOrderBySimilarity, Candidates: len(similarNamesOrderedByLevenshtein), Matched: true})
 
 	// Return the canonical name combined with similar names ordered by levenshtein.
-	canonicalEntity, err := SearchCanonicalName(name, SimilarityThreshold, allNames, exactMetaphoneMatches, similarNamesOrderedByLevenshtein)
+	canonicalEntity, err := SearchCanonicalName(name, opts, idx, exactMetaphoneMatches, similarNamesOrderedByLevenshtein, trace)
 	if err != nil {
 		return nil, fmt.Errorf("error failed to find canonical name: %w", err)
 	}
@@ -161,24 +252,72 @@ func GetSimilarMatch(name string, allNames []NameType) (*NameType, error) {
 	return canonicalEntity, nil
 }
 
-// SearchSimilarMetaphone returns a slice of NameType elements that have a metaphone similar to the given paradigmMetaphone
-func SearchSimilarMetaphone(paradigmMetaphone string, allNames []NameType) []NameType {
-	// create an empty slice to store the return values
-	var returnNames []NameType
-	// iterate over all the names in allNames
-	for _, name := range allNames {
-		// if the metaphone of the name is similar to the given paradigmMetaphone
-		if metaphone.IsMetaphoneSimilar(paradigmMetaphone, name.Metaphone) {
-			// add the name to the returnNames slice
-			returnNames = append(returnNames, name)
+// GetSimilarCandidates returns up to top candidates for a given name ranked by similarity. A NameType is a candidate
+// if it is an exact match, if it lists the name as a variation or if its code on the given phonetic algorithm is equal
+// or similar to the name code.
+func GetSimilarCandidates(name string, idx *NameIndex, top int, algorithm string) ([]Candidate, error) {
+	n := strings.ToUpper(name)
+	encoder, err := GetEncoder(algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("error getting candidates: %w", err)
+	}
+	nameMetaphone := encoder.Encode(name)
+
+	// Collect the name types of every lookup, keeping the strongest match type of each one
+	matchTypes := make(map[uint]string)
+	var nameTypes []NameType
+	collect := func(matchType string, names []NameType) {
+		for _, nt := range names {
+			if _, ok := matchTypes[nt.ID]; !ok {
+				matchTypes[nt.ID] = matchType
+				nameTypes = append(nameTypes, nt)
+			}
 		}
 	}
-	// return the slice of NameType elements that have a metaphone similar to the given paradigmMetaphone
-	return returnNames
+	if nt, ok := idx.ByName(n); ok {
+		collect(MatchExact, []NameType{nt})
+	}
+	collect(MatchVariation, idx.ByVariation(n))
+	collect(MatchMetaphone, idx.ByCode(algorithm, nameMetaphone))
+	collect(MatchSimilarMetaphone, idx.BySimilarCode(algorithm, nameMetaphone))
+
+	if len(nameTypes) == 0 {
+		return nil, fmt.Errorf("error no candidates found for %q", name)
+	}
+
+	// Keep the table order so ties are ranked as a linear scan would
+	sort.Slice(nameTypes, func(i, j int) bool { return nameTypes[i].ID < nameTypes[j].ID })
+
+	candidates := make([]Candidate, 0, len(nameTypes))
+	for _, nt := range nameTypes {
+		candidates = append(candidates, Candidate{
+			Name:           nt.Name,
+			Classification: nt.Classification,
+			Metaphone:      nt.Code(algorithm),
+			Similarity:     metaphone.SimilarityBetweenWords(strings.ToLower(name), strings.ToLower(nt.Name)),
+			MatchType:      matchTypes[nt.ID],
+		})
+	}
+
+	// Order the candidates and keep only the top ones
+	candidates = OrderCandidates(candidates)
+	if len(candidates) > top {
+		candidates = candidates[:top]
+	}
+
+	return candidates, nil
 }
 
-// SearchSimilarNames returns a slice of NameLevenshtein elements that have a similarity score higher than the given threshold to the given paradigmName
-func SearchSimilarNames(paradigmName string, allNames []NameType, threshold float32) []NameSimilarity {
+// SearchSimilarMetaphone returns a slice of NameType elements that have a code of the given phonetic algorithm similar to the given paradigmMetaphone
+func SearchSimilarMetaphone(paradigmMetaphone, algorithm string, idx *NameIndex) []NameType {
+	return idx.BySimilarCode(algorithm, paradigmMetaphone)
+}
+
+// SearchSimilarNames returns a slice of NameLevenshtein elements that have a similarity score higher than the threshold of opts to the given paradigmName.
+// The stages entered are recorded on trace, which may be nil.
+func SearchSimilarNames(paradigmName string, allNames []NameType, opts MatchOptions, trace *MatchTrace) []NameSimilarity {
+	threshold := opts.Threshold
+
 	// create an empty slice to store the return values
 	var similarNames []NameSimilarity
 
@@ -210,11 +349,13 @@ func SearchSimilarNames(paradigmName string, allNames []NameType, threshold floa
 		}
 	}
 
+	trace.addStage(TraceStage{Stage: StageSimilarNames, Candidates: len(similarNames), Threshold: threshold, Matched: len(similarNames) != 0})
+
 	// if no names were found with a similarity score higher than the threshold, try again with a lower threshold
-	if len(similarNames) == 0 {
+	if len(similarNames) == 0 && opts.relax() {
 		for _, name := range allNames {
 			similarity := metaphone.SimilarityBetweenWords(strings.ToLower(paradigmName), strings.ToLower(name.Name))
-			if similarity >= threshold-0.1 {
+			if similarity >= opts.relaxedThreshold() {
 				similarName := NameSimilarity{Name: name.Name, Similarity: similarity}
 				similarNames = append(similarNames, similarName)
 				for _, vw := range strings.Split(name.NameVariations, "|") {
@@ -225,15 +366,18 @@ func SearchSimilarNames(paradigmName string, allNames []NameType, threshold floa
 				}
 			}
 		}
+		trace.addStage(TraceStage{Stage: StageSimilarNamesRelaxed, Candidates: len(similarNames), Threshold: opts.relaxedThreshold(), Matched: len(similarNames) != 0})
 		return similarNames
 	}
 	return similarNames
 }
 
-// SearchCanonicalName searches for a canonical name in a list of names using a given threshold for similarity matching.
-func SearchCanonicalName(paradigmName string, threshold float32, allNames []NameType, matchingMetaphoneNames []NameType, nameVariations []string) (*NameType, error) {
+// SearchCanonicalName searches for a canonical name in a list of names using the threshold of opts for similarity matching.
+// The stages entered and the reason the canonical name was chosen are recorded on trace, which may be nil.
+func SearchCanonicalName(paradigmName string, opts MatchOptions, idx *NameIndex, matchingMetaphoneNames []NameType, nameVariations []string, trace *MatchTrace) (*NameType, error) {
 	// Convert the input name to uppercase.
 	n := strings.ToUpper(paradigmName)
+	threshold := opts.Threshold
 
 	// Transform the nameVariations into a string to be returned.
 	var rNv string
@@ -245,89 +389,85 @@ func SearchCanonicalName(paradigmName string, threshold float32, allNames []Name
 	for _, similarName := range matchingMetaphoneNames {
 		if similarName.Name == n {
 			// If an exact match is found, add the name variations and return the result.
+			trace.addStage(TraceStage{Stage: StageCanonicalExactMetaphone, Candidates: len(matchingMetaphoneNames), Matched: true})
+			trace.setWinner(similarName.Name, "name is equal to a name with matching metaphone")
 			similarName.NameVariations = rNv
 			return &similarName, nil
 		}
 	}
+	trace.addStage(TraceStage{Stage: StageCanonicalExactMetaphone, Candidates: len(matchingMetaphoneNames)})
 
 	// Search for similar names on matchingMetaphoneNames.
 	for _, similarName := range matchingMetaphoneNames {
 		// Convert the name variations to uppercase for comparison.
 		sn := strings.ToUpper(similarName.NameVariations)
-		if metaphone.SimilarityBetweenWords(n, sn) >= threshold {
+		if similarity := metaphone.SimilarityBetweenWords(n, sn); similarity >= threshold {
 			// If a similar name is found, add the name variations and return the result.
+			trace.addStage(TraceStage{Stage: StageCanonicalSimilarMetaphone, Candidates: len(matchingMetaphoneNames), Threshold: threshold, Matched: true})
+			trace.setWinner(similarName.Name, fmt.Sprintf("name variations of a name with matching metaphone have similarity %.2f", similarity))
 			similarName.NameVariations = rNv
 			return &similarName, nil
 		}
 	}
+	trace.addStage(TraceStage{Stage: StageCanonicalSimilarMetaphone, Candidates: len(matchingMetaphoneNames), Threshold: threshold})
 
 	// Search for exact match on nameVariations.
 	for _, similarName := range nameVariations {
 		// Convert the name variations to uppercase for comparison.
 		sn := strings.ToUpper(similarName)
 		if sn == n {
-			// If an exact match is found, search for the corresponding name in allNames, add the name variations, and return the result.
-			for _, name := range allNames {
-				if name.Name == n {
-					name.NameVariations = rNv
-					return &name, nil
-				}
+			// If an exact match is found, search for the corresponding name on the index, add the name variations, and return the result.
+			if name, ok := idx.ByName(n); ok {
+				trace.addStage(TraceStage{Stage: StageCanonicalExactVariation, Candidates: len(nameVariations), Matched: true})
+				trace.setWinner(name.Name, "name is equal to a similar name")
+				name.NameVariations = rNv
+				return &name, nil
 			}
 		}
 	}
+	trace.addStage(TraceStage{Stage: StageCanonicalExactVariation, Candidates: len(nameVariations)})
 
 	// Search for similar names on nameVariations.
 	for _, similarName := range nameVariations {
 		// Convert the name variations to uppercase for comparison.
 		sn := strings.ToUpper(similarName)
-		if metaphone.SimilarityBetweenWords(n, sn) >= threshold {
-			// If a similar name is found, search for the corresponding name in allNames, add the name variations, and return the result.
-			for _, name := range allNames {
-				if name.Name == sn {
-					name.NameVariations = rNv
-					return &name, nil
-				}
+		if similarity := metaphone.SimilarityBetweenWords(n, sn); similarity >= threshold {
+			// If a similar name is found, search for the corresponding name on the index, add the name variations, and return the result.
+			if name, ok := idx.ByName(sn); ok {
+				trace.addStage(TraceStage{Stage: StageCanonicalSimilarVariation, Candidates: len(nameVariations), Threshold: threshold, Matched: true})
+				trace.setWinner(name.Name, fmt.Sprintf("highest ranked similar name with similarity %.2f", similarity))
+				name.NameVariations = rNv
+				return &name, nil
 			}
 		}
 	}
+	trace.addStage(TraceStage{Stage: StageCanonicalSimilarVariation, Candidates: len(nameVariations), Threshold: threshold})
 
 	// If none of the above searches succeed, search for similar names on nameVariations with a lower threshold.
+	if !opts.relax() {
+		return &NameType{}, errors.New("couldn't find canonical name")
+	}
 	for _, similarName := range nameVariations {
 		// Convert the name variations to uppercase for comparison.
 		sn := strings.ToUpper(similarName)
-		if metaphone.SimilarityBetweenWords(n, sn) >= threshold-0.1 {
-			// If a similar name is found, search for the corresponding name in allNames, add the name variations, and return the result.
-			for _, name := range allNames {
-				if name.Name == sn {
-					name.NameVariations = rNv
-					return &name, nil
-				}
+		if similarity := metaphone.SimilarityBetweenWords(n, sn); similarity >= opts.relaxedThreshold() {
+			// If a similar name is found, search for the corresponding name on the index, add the name variations, and return the result.
+			if name, ok := idx.ByName(sn); ok {
+				trace.addStage(TraceStage{Stage: StageCanonicalRelaxedVariation, Candidates: len(nameVariations), Threshold: opts.relaxedThreshold(), Matched: true})
+				trace.setWinner(name.Name, fmt.Sprintf("highest ranked similar name with similarity %.2f on relaxed threshold", similarity))
+				name.NameVariations = rNv
+				return &name, nil
 			}
 		}
 	}
+	trace.addStage(TraceStage{Stage: StageCanonicalRelaxedVariation, Candidates: len(nameVariations), Threshold: opts.relaxedThreshold()})
 
 	// If no match is found, return an error.
 	return &NameType{}, errors.New("couldn't find canonical name")
 }
 
-// SearchCacheMetaphone searches for all NameType objects in the cache that have a matching metaphone value
-// and returns them as a slice
-func SearchCacheMetaphone(metaphone string, cache []NameType) []NameType {
-	// create an empty slice to hold the NameType objects with matching metaphone values
-	var nameTypes []NameType
-	// iterate over the cache and look for NameType objects with metaphone values that match the given metaphone value
-	for _, c := range cache {
-		if c.Metaphone == metaphone {
-			// if a match is found, append the NameType object to the slice
-			nameTypes = append(nameTypes, c)
-		}
-	}
-
-	// if at least one NameType object is found, return the slice
-	if len(nameTypes) != 0 {
-		return nameTypes
-	}
-
-	// if no NameType object is found, return nil
-	return nil
+// SearchCacheMetaphone searches for all NameType objects in the index that have a matching code of the given phonetic
+// algorithm and returns them as a slice
+func SearchCacheMetaphone(metaphone, algorithm string, idx *NameIndex) []NameType {
+	return idx.ByCode(algorithm, metaphone)
 }
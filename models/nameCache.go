@@ -0,0 +1,262 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChangeGapTimeout is how long a gap on the ids of the change log is polled again. A gap is a change whose
+// transaction had not committed when a later change was read, or a rolled back change that never appears.
+const ChangeGapTimeout = time.Minute
+
+// MaxChangeGaps bounds the gaps polled again, so ids skipped by the database don't pile up
+const MaxChangeGaps = 1000
+
+// ReloadChangeGaps is how many changes before the latest one are polled again after a load, as their transactions
+// may not have committed when the names were read
+const ReloadChangeGaps = 100
+
+// ChangePageSize is how many changes of the change log a sync reads at once
+const ChangePageSize = 1000
+
+// MaxSyncChanges is the backlog of changes above which a sync reloads the whole cache instead of patching it
+const MaxSyncChanges = 10000
+
+// NameIDChunkSize is how many names are read at once by their ids
+const NameIDChunkSize = 1000
+
+// NameSnapshot is an immutable version of the name index served by the NameCache
+type NameSnapshot struct {
+	Index    *NameIndex `json:"-"`
+	Version  uint64
+	Names    int
+	LoadedAt time.Time
+	// ChangeID is the id of the latest change of the change log applied to the snapshot
+	ChangeID uint
+}
+
+// NameCache is the process-wide cache of the name index. Readers get an immutable snapshot that is swapped atomically,
+// so a request never sees a half built index. Writes patch a copy of the snapshot once they are committed and the
+// writes of other instances are picked up by Sync from the change log.
+type NameCache struct {
+	source NameSource
+
+	// mu serializes the loads and the patches so a patch is never overwritten by a load that started before it
+	mu       sync.Mutex
+	version  uint64
+	snapshot atomic.Value // *NameSnapshot, nil when the cache is empty
+	gaps     map[uint]time.Time
+}
+
+// NewNameCache returns an empty cache that loads its names and their changes from source
+func NewNameCache(source NameSource) *NameCache {
+	return &NameCache{source: source, gaps: make(map[uint]time.Time)}
+}
+
+// Snapshot returns the current snapshot of the cache, loading it if the cache is empty
+func (c *NameCache) Snapshot() (*NameSnapshot, error) {
+	if s := c.current(); s != nil {
+		return s, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another request may have loaded the cache while this one was waiting
+	if s := c.current(); s != nil {
+		return s, nil
+	}
+	return c.reload()
+}
+
+// Reload loads the names again and swaps the snapshot of the cache
+func (c *NameCache) Reload() (*NameSnapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reload()
+}
+
+// Apply patches a copy of the current snapshot with the given name types inserted or replaced and the name types with
+// the given ids removed, then swaps it. An empty cache is left empty, it loads the committed names when needed.
+func (c *NameCache) Apply(upserts []NameType, deletes []uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.current()
+	if current == nil {
+		return
+	}
+	c.swap(current, upserts, deletes, current.ChangeID)
+}
+
+// Sync applies the changes of the change log written after the latest change applied to the snapshot, such as the
+// writes of other instances. It returns the number of names inserted, replaced or removed. When the changes can't be
+// read or there are more than MaxSyncChanges of them, the whole cache is reloaded instead, so it never stays stale.
+func (c *NameCache) Sync() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.current()
+	if current == nil {
+		return 0, nil
+	}
+
+	// Read the new changes and the gaps left by the previous syncs
+	gaps := make([]uint, 0, len(c.gaps))
+	for id := range c.gaps {
+		gaps = append(gaps, id)
+	}
+	var changes []NameChange
+	after := current.ChangeID
+	for {
+		page, err := c.source.ChangesAfter(after, gaps, ChangePageSize)
+		if err != nil {
+			return c.resync(err)
+		}
+		changes = append(changes, page...)
+		if len(page) < ChangePageSize {
+			break
+		}
+		if len(changes) > MaxSyncChanges {
+			return c.resync(fmt.Errorf("more than %d changes to apply", MaxSyncChanges))
+		}
+
+		// The gaps are older than the new changes, so the first page holds all of them
+		gaps = nil
+		if last := page[len(page)-1].ID; last > after {
+			after = last
+		}
+	}
+
+	// Track the ids skipped by the new changes, their transactions may still commit
+	now := time.Now()
+	changeID := current.ChangeID
+	changed := make(map[uint]bool)
+	var nameTypeIDs []uint
+	for _, ch := range changes {
+		delete(c.gaps, ch.ID)
+		if ch.ID > changeID {
+			for id := changeID + 1; id < ch.ID && len(c.gaps) < MaxChangeGaps; id++ {
+				c.gaps[id] = now
+			}
+			changeID = ch.ID
+		}
+		if !changed[ch.NameTypeID] {
+			changed[ch.NameTypeID] = true
+			nameTypeIDs = append(nameTypeIDs, ch.NameTypeID)
+		}
+	}
+	for id, seen := range c.gaps {
+		if now.Sub(seen) > ChangeGapTimeout {
+			delete(c.gaps, id)
+		}
+	}
+	if len(nameTypeIDs) == 0 {
+		return 0, nil
+	}
+
+	// Apply the current state of every changed name
+	names, err := c.source.NamesByID(nameTypeIDs)
+	if err != nil {
+		return c.resync(err)
+	}
+	var upserts []NameType
+	for _, n := range names {
+		if !n.DeletedAt.Valid {
+			upserts = append(upserts, n)
+			delete(changed, n.ID)
+		}
+	}
+	deletes := make([]uint, 0, len(changed))
+	for id := range changed {
+		deletes = append(deletes, id)
+	}
+
+	c.swap(current, upserts, deletes, changeID)
+
+	return len(upserts) + len(deletes), nil
+}
+
+// resync reloads the whole cache when a sync can't patch it, and returns the number of names loaded. The caller must
+// hold mu.
+func (c *NameCache) resync(cause error) (int, error) {
+	s, err := c.reload()
+	if err != nil {
+		return 0, fmt.Errorf("error syncing name cache: %v, then %w", cause, err)
+	}
+	log.Printf("-	Name cache reloaded with %d names instead of synced: %v", s.Names, cause)
+	return s.Names, nil
+}
+
+// StartSync creates a goroutine that syncs the cache every time the given ticker is triggered
+func (c *NameCache) StartSync(ticker *time.Ticker) {
+	go func() {
+		for range ticker.C {
+			if _, err := c.Sync(); err != nil {
+				log.Printf("Error syncing name cache: %v", err)
+			}
+		}
+	}()
+}
+
+// Loaded returns the current snapshot without loading it, false if the cache is empty
+func (c *NameCache) Loaded() (*NameSnapshot, bool) {
+	s := c.current()
+	return s, s != nil
+}
+
+// current returns the current snapshot, nil if the cache is empty
+func (c *NameCache) current() *NameSnapshot {
+	s, _ := c.snapshot.Load().(*NameSnapshot)
+	return s
+}
+
+// reload loads the names and stores a new snapshot. The caller must hold mu.
+func (c *NameCache) reload() (*NameSnapshot, error) {
+	// Read the change log first, the changes written while the names load are applied again by the next sync
+	changeID, err := c.source.LastChangeID()
+	if err != nil {
+		return nil, fmt.Errorf("error loading name cache: %w", err)
+	}
+	names, err := c.source.AllNames()
+	if err != nil {
+		return nil, fmt.Errorf("error loading name cache: %w", err)
+	}
+
+	c.version++
+	s := &NameSnapshot{
+		Index:    NewNameIndex(names),
+		Version:  c.version,
+		Names:    len(names),
+		LoadedAt: time.Now(),
+		ChangeID: changeID,
+	}
+	c.snapshot.Store(s)
+
+	c.gaps = make(map[uint]time.Time)
+	for id := changeID; id > 0 && changeID-id < ReloadChangeGaps; id-- {
+		c.gaps[id] = s.LoadedAt
+	}
+
+	return s, nil
+}
+
+// swap stores a new snapshot with the changes applied to the index of current and the given change id.
+// The caller must hold mu.
+func (c *NameCache) swap(current *NameSnapshot, upserts []NameType, deletes []uint, changeID uint) {
+	index := current.Index.Apply(upserts, deletes)
+
+	c.version++
+	s := &NameSnapshot{
+		Index:    index,
+		Version:  c.version,
+		Names:    index.Len(),
+		LoadedAt: current.LoadedAt,
+		ChangeID: changeID,
+	}
+	c.snapshot.Store(s)
+}
@@ -0,0 +1,321 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakeSource is an in-memory NameSource. Changes on hidden are not returned yet, like the changes of transactions
+// that didn't commit.
+type fakeSource struct {
+	names   map[uint]NameType
+	changes []NameChange
+	hidden  map[uint]bool
+	err     error
+	// changesErr fails the reads of the change log only
+	changesErr error
+
+	loads, pages int
+}
+
+func newFakeSource(names []NameType) *fakeSource {
+	f := &fakeSource{names: make(map[uint]NameType), hidden: make(map[uint]bool)}
+	for _, n := range names {
+		f.names[n.ID] = n
+	}
+	return f
+}
+
+// write stores n and records its change, returning the id of the change
+func (f *fakeSource) write(n NameType, operation string) uint {
+	if operation == ChangeDelete {
+		n.DeletedAt = gorm.DeletedAt{Valid: true}
+	}
+	f.names[n.ID] = n
+	id := uint(len(f.changes) + 1)
+	f.changes = append(f.changes, NameChange{ID: id, NameTypeID: n.ID, Operation: operation})
+	return id
+}
+
+func (f *fakeSource) AllNames() ([]NameType, error) {
+	f.loads++
+	if f.err != nil {
+		return nil, f.err
+	}
+	var names []NameType
+	for _, n := range f.names {
+		if !n.DeletedAt.Valid {
+			names = append(names, n)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].ID < names[j].ID })
+	return names, nil
+}
+
+func (f *fakeSource) LastChangeID() (uint, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	var last uint
+	for _, ch := range f.changes {
+		if !f.hidden[ch.ID] {
+			last = ch.ID
+		}
+	}
+	return last, nil
+}
+
+func (f *fakeSource) ChangesAfter(id uint, ids []uint, limit int) ([]NameChange, error) {
+	f.pages++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.changesErr != nil {
+		return nil, f.changesErr
+	}
+	var changes []NameChange
+	for _, ch := range f.changes {
+		if len(changes) == limit {
+			break
+		}
+		if !f.hidden[ch.ID] && (ch.ID > id || containsID(ids, ch.ID)) {
+			changes = append(changes, ch)
+		}
+	}
+	return changes, nil
+}
+
+func (f *fakeSource) NamesByID(ids []uint) ([]NameType, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var names []NameType
+	for _, id := range ids {
+		if n, ok := f.names[id]; ok {
+			names = append(names, n)
+		}
+	}
+	return names, nil
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheVersion returns the version of the loaded snapshot of the cache, 0 if it is empty
+func cacheVersion(c *NameCache) uint64 {
+	if s, ok := c.Loaded(); ok {
+		return s.Version
+	}
+	return 0
+}
+
+// newName returns a name type with the given id
+func newName(id uint, name, classification string) NameType {
+	n := NameType{Name: name, Classification: classification}
+	n.ID = id
+	return n
+}
+
+func TestNameCacheSnapshot(t *testing.T) {
+	names := testNames()
+	src := newFakeSource(names)
+	c := NewNameCache(src)
+
+	// An empty cache is left empty by Apply
+	c.Apply([]NameType{names[0]}, nil)
+	if cacheVersion(c) != 0 || src.loads != 0 {
+		t.Fatalf("Apply on an empty cache = version %d after %d loads, want 0 and 0", cacheVersion(c), src.loads)
+	}
+
+	s, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+	if s.Names != len(names) || s.Version != 1 {
+		t.Fatalf("Snapshot() = %+v, want %d names on version 1", s, len(names))
+	}
+	if again, _ := c.Snapshot(); again != s || src.loads != 1 {
+		t.Fatalf("second Snapshot() loaded %d times, want the cached snapshot", src.loads)
+	}
+
+	// Apply patches a copy, the snapshot already served is left untouched
+	c.Apply([]NameType{newName(20, "CARLOS", "M")}, []uint{names[0].ID})
+	patched, _ := c.Snapshot()
+	if patched.Version != 2 || patched.Names != len(names) || !patched.LoadedAt.Equal(s.LoadedAt) {
+		t.Fatalf("patched snapshot = %+v, want version 2 with %d names", patched, len(names))
+	}
+	if _, ok := patched.Index.ByName("CARLOS"); !ok {
+		t.Fatal("patched snapshot lacks CARLOS")
+	}
+	if _, ok := patched.Index.ByName("ANA"); ok {
+		t.Fatal("patched snapshot still has ANA")
+	}
+	if _, ok := s.Index.ByName("ANA"); !ok {
+		t.Fatal("Apply changed the snapshot already served")
+	}
+
+	// Reload loads the names again
+	if s, err := c.Reload(); err != nil || s.Version != 3 || src.loads != 2 {
+		t.Fatalf("Reload() = %+v, %v after %d loads, want version 3 after 2 loads", s, err, src.loads)
+	}
+
+	// A failing load keeps the snapshot
+	src.err = errors.New("connection refused")
+	if _, err := c.Reload(); err == nil {
+		t.Fatal("Reload() with a failing source succeeded, want an error")
+	}
+	if cacheVersion(c) != 3 {
+		t.Fatalf("version after a failed reload = %d, want 3", cacheVersion(c))
+	}
+}
+
+func TestNameCacheSync(t *testing.T) {
+	names := testNames()
+	src := newFakeSource(names)
+	c := NewNameCache(src)
+
+	// An empty cache has nothing to sync
+	if n, err := c.Sync(); n != 0 || err != nil || src.pages != 0 {
+		t.Fatalf("Sync() on an empty cache = %d, %v after %d reads, want 0", n, err, src.pages)
+	}
+	if _, err := c.Snapshot(); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+
+	// Another instance renamed MARIA twice, deleted PEDRO and added CARLOS
+	maria := names[5]
+	maria.Name, maria.Metaphone = "MARIAH", ""
+	src.write(maria, ChangeUpsert)
+	maria.Name, maria.Metaphone = "MARYA", ""
+	src.write(maria, ChangeUpsert)
+	src.write(names[7], ChangeDelete)
+	last := src.write(newName(20, "CARLOS", "M"), ChangeUpsert)
+
+	if n, err := c.Sync(); err != nil || n != 3 {
+		t.Fatalf("Sync() = %d, %v, want 3", n, err)
+	}
+	s, _ := c.Loaded()
+	if s.Version != 2 || s.Names != len(names) || s.ChangeID != last || src.loads != 1 {
+		t.Fatalf("synced snapshot = %+v after %d loads, want version 2 with %d names at change %d patched", s, src.loads, len(names), last)
+	}
+	for name, want := range map[string]bool{"MARYA": true, "MARIAH": false, "MARIA": false, "PEDRO": false, "CARLOS": true, "ANA": true} {
+		if _, ok := s.Index.ByName(name); ok != want {
+			t.Errorf("synced snapshot has %s = %v, want %v", name, ok, want)
+		}
+	}
+
+	// Nothing is left to apply
+	if n, err := c.Sync(); n != 0 || err != nil || cacheVersion(c) != 2 {
+		t.Fatalf("second Sync() = %d, %v on version %d, want 0 on version 2", n, err, cacheVersion(c))
+	}
+}
+
+func TestNameCacheSyncGaps(t *testing.T) {
+	src := newFakeSource(testNames())
+	c := NewNameCache(src)
+	if _, err := c.Snapshot(); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+
+	// The transaction of the first change commits after the second one is read
+	gap := src.write(newName(20, "CARLOS", "M"), ChangeUpsert)
+	src.write(newName(21, "DIEGO", "M"), ChangeUpsert)
+	src.hidden[gap] = true
+	if n, err := c.Sync(); err != nil || n != 1 {
+		t.Fatalf("Sync() = %d, %v, want 1", n, err)
+	}
+
+	delete(src.hidden, gap)
+	if n, err := c.Sync(); err != nil || n != 1 {
+		t.Fatalf("Sync() after the gap committed = %d, %v, want 1", n, err)
+	}
+	s, _ := c.Loaded()
+	if _, ok := s.Index.ByName("CARLOS"); !ok {
+		t.Fatal("change committed late is not on the cache")
+	}
+}
+
+func TestNameCacheSyncPages(t *testing.T) {
+	src := newFakeSource(nil)
+	c := NewNameCache(src)
+	before, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+
+	// More changes than a page, less than MaxSyncChanges, are patched page by page
+	count := ChangePageSize*2 + 500
+	for i := 0; i < count; i++ {
+		src.write(newName(uint(i+1), testName(i), "M"), ChangeUpsert)
+	}
+	if n, err := c.Sync(); err != nil || n != count {
+		t.Fatalf("Sync() = %d, %v, want %d", n, err, count)
+	}
+	after, _ := c.Loaded()
+	if after.Names != count || !after.LoadedAt.Equal(before.LoadedAt) || src.loads != 1 || src.pages != 3 {
+		t.Fatalf("cache has %d names after %d loads and %d pages, want %d names patched from 3 pages", after.Names, src.loads, src.pages, count)
+	}
+}
+
+func TestNameCacheSyncReload(t *testing.T) {
+	src := newFakeSource(testNames())
+	c := NewNameCache(src)
+	if _, err := c.Snapshot(); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+
+	// A backlog above MaxSyncChanges reloads the cache instead of patching it
+	count := MaxSyncChanges + ChangePageSize
+	for i := 0; i < count; i++ {
+		src.write(newName(uint(100+i), testName(i), "F"), ChangeUpsert)
+	}
+	total := len(testNames()) + count
+	if n, err := c.Sync(); err != nil || n != total || src.loads != 2 {
+		t.Fatalf("Sync() of a large backlog = %d, %v after %d loads, want a reload of %d names", n, err, src.loads, total)
+	}
+	s, _ := c.Loaded()
+	if n, ok := s.Index.ByName(testName(count - 1)); !ok || n.Classification != "F" || s.ChangeID != uint(count) {
+		t.Fatalf("ByName(%s) = %+v, %v at change %d, want the last name at change %d", testName(count-1), n, ok, s.ChangeID, count)
+	}
+
+	// So does a change log that can't be read
+	src.write(newName(1, "ANNA", "F"), ChangeUpsert)
+	src.changesErr = errors.New("relation name_changes does not exist")
+	if n, err := c.Sync(); err != nil || n != total || src.loads != 3 {
+		t.Fatalf("Sync() with a failing change log = %d, %v after %d loads, want a reload of %d names", n, err, src.loads, total)
+	}
+	s, _ = c.Loaded()
+	if _, ok := s.Index.ByName("ANNA"); !ok {
+		t.Fatal("reloaded cache lacks ANNA")
+	}
+
+	// When the reload fails too the snapshot is kept
+	src.err = errors.New("connection refused")
+	if _, err := c.Sync(); err == nil {
+		t.Fatal("Sync() with a failing source succeeded, want an error")
+	}
+	if cacheVersion(c) != 3 {
+		t.Fatalf("version after a failed sync = %d, want 3", cacheVersion(c))
+	}
+}
+
+// testName returns a distinct name of letters only for i
+func testName(i int) string {
+	name := "NAME"
+	for {
+		name += string(rune('A' + i%26))
+		i /= 26
+		if i == 0 {
+			return name
+		}
+	}
+}
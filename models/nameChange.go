@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Operations of a NameChange
+const (
+	ChangeUpsert = "upsert"
+	ChangeDelete = "delete"
+)
+
+// NameChange is a row of the change log of the name_types table. Every mutation of a NameType writes one in the same
+// transaction, so the instances that poll the log can patch their caches.
+type NameChange struct {
+	ID         uint      `gorm:"primarykey"`
+	NameTypeID uint      `gorm:"not null;index"`
+	Operation  string    `gorm:"size:16;not null"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+// recordChange writes the change of the name type with the given id to the change log
+func recordChange(tx *gorm.DB, nameTypeID uint, operation string) error {
+	err := tx.Create(&NameChange{NameTypeID: nameTypeID, Operation: operation}).Error
+	if err != nil {
+		return fmt.Errorf("error recording name change: %w", err)
+	}
+	return nil
+}
+
+// RecordChanges writes the changes of the name types with the given ids to the change log, in the given transaction
+func RecordChanges(tx *gorm.DB, nameTypeIDs []uint, operation string) error {
+	if len(nameTypeIDs) == 0 {
+		return nil
+	}
+
+	changes := make([]NameChange, len(nameTypeIDs))
+	for i, id := range nameTypeIDs {
+		changes[i] = NameChange{NameTypeID: id, Operation: operation}
+	}
+	err := tx.CreateInBatches(&changes, 1000).Error
+	if err != nil {
+		return fmt.Errorf("error recording name changes: %w", err)
+	}
+	return nil
+}
+
+// PruneNameChanges deletes the changes of the change log older than the given time
+func PruneNameChanges(before time.Time) error {
+	err := DB.Where("created_at < ?", before).Delete(&NameChange{}).Error
+	if err != nil {
+		return fmt.Errorf("error pruning name changes: %w", err)
+	}
+	return nil
+}
+
+// StartPruneNameChanges creates a goroutine that deletes the changes older than retention every time the given ticker
+// is triggered
+func StartPruneNameChanges(ticker *time.Ticker, retention time.Duration) {
+	go func() {
+		for range ticker.C {
+			if err := PruneNameChanges(time.Now().Add(-retention)); err != nil {
+				log.Printf("Error pruning name changes: %v", err)
+			}
+		}
+	}()
+}
+
+// NameSource is where the NameCache loads the names and the changes of the names from
+type NameSource interface {
+	// AllNames returns all non-deleted names
+	AllNames() ([]NameType, error)
+	// LastChangeID returns the id of the latest change of the change log, 0 if it is empty
+	LastChangeID() (uint, error)
+	// ChangesAfter returns at most limit changes with an id greater than the given one or within the given ids, ordered
+	// by id
+	ChangesAfter(id uint, ids []uint, limit int) ([]NameChange, error)
+	// NamesByID returns the names with the given ids, deleted names included
+	NamesByID(ids []uint) ([]NameType, error)
+}
+
+// DBNameSource is the NameSource of the database
+type DBNameSource struct{}
+
+func (DBNameSource) AllNames() ([]NameType, error) {
+	return GetAllNames()
+}
+
+func (DBNameSource) LastChangeID() (uint, error) {
+	var id uint
+	err := DB.Model(&NameChange{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	if err != nil {
+		return 0, fmt.Errorf("error getting last name change: %w", err)
+	}
+	return id, nil
+}
+
+func (DBNameSource) ChangesAfter(id uint, ids []uint, limit int) ([]NameChange, error) {
+	query := DB.Where("id > ?", id)
+	if len(ids) != 0 {
+		query = query.Or("id IN ?", ids)
+	}
+
+	var changes []NameChange
+	err := query.Order("id").Limit(limit).Find(&changes).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting name changes: %w", err)
+	}
+	return changes, nil
+}
+
+// NamesByID reads the names in chunks of NameIDChunkSize ids, below the bound variables limit of the databases
+func (DBNameSource) NamesByID(ids []uint) ([]NameType, error) {
+	var names []NameType
+	for start := 0; start < len(ids); start += NameIDChunkSize {
+		end := start + NameIDChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		var chunk []NameType
+		err := DB.Unscoped().Where("id IN ?", ids[start:end]).Find(&chunk).Error
+		if err != nil {
+			return nil, fmt.Errorf("error getting names by id: %w", err)
+		}
+		names = append(names, chunk...)
+	}
+	return names, nil
+}
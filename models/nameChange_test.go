@@ -0,0 +1,108 @@
+package models_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+// createNames creates the names with the given classification
+func createNames(t *testing.T, classification string, names ...string) []models.NameType {
+	t.Helper()
+	created := make([]models.NameType, 0, len(names))
+	for _, name := range names {
+		n := models.NameType{Name: name, Classification: classification}
+		if err := n.CreateName(); err != nil {
+			t.Fatalf("error creating name %s: %v", name, err)
+		}
+		created = append(created, n)
+	}
+	return created
+}
+
+// The writes of an instance reach the cache of another one through the change log
+func TestDBNameSourceSync(t *testing.T) {
+	dbtest.Open(t)
+	names := createNames(t, models.ClassificationFemale, "MARIA", "ANA", "JULIA")
+	cache := models.NewNameCache(models.DBNameSource{})
+	if _, err := cache.Snapshot(); err != nil {
+		t.Fatalf("error loading cache: %v", err)
+	}
+
+	// Writes of another instance, not applied to the cache
+	createNames(t, models.ClassificationMale, "PEDRO")
+	if _, err := names[0].UpdateName(models.NameType{Classification: models.ClassificationUnisex}); err != nil {
+		t.Fatalf("error updating name: %v", err)
+	}
+	if err := names[1].DeleteName(); err != nil {
+		t.Fatalf("error deleting name: %v", err)
+	}
+
+	// The first sync after a load also applies again the names changed right before it, JULIA included
+	n, err := cache.Sync()
+	if err != nil || n != 4 {
+		t.Fatalf("Sync() = %d, %v, want 4 names", n, err)
+	}
+	snapshot, err := cache.Snapshot()
+	if err != nil {
+		t.Fatalf("error getting snapshot: %v", err)
+	}
+	if _, ok := snapshot.Index.ByName("PEDRO"); !ok {
+		t.Error("created name not synced")
+	}
+	if maria, _ := snapshot.Index.ByName("MARIA"); maria.Classification != models.ClassificationUnisex {
+		t.Errorf("updated name classification = %q, want %q", maria.Classification, models.ClassificationUnisex)
+	}
+	if _, ok := snapshot.Index.ByName("ANA"); ok {
+		t.Error("deleted name still cached")
+	}
+	if snapshot.Names != 3 {
+		t.Errorf("snapshot has %d names, want 3", snapshot.Names)
+	}
+
+	// Nothing changed since the sync
+	if n, err = cache.Sync(); err != nil || n != 0 {
+		t.Fatalf("second Sync() = %d, %v, want 0 names", n, err)
+	}
+}
+
+// NamesByID reads more ids than a chunk, the deleted names included so their deletes are applied
+func TestDBNameSourceNamesByID(t *testing.T) {
+	db := dbtest.Open(t)
+	total := models.NameIDChunkSize*2 + 10
+	names := make([]models.NameType, total)
+	for i := range names {
+		names[i] = models.NameType{Name: fmt.Sprintf("NAME%d", i), Classification: models.ClassificationUnknown}
+	}
+	if err := db.CreateInBatches(&names, 500).Error; err != nil {
+		t.Fatalf("error creating names: %v", err)
+	}
+	if err := db.Delete(&names[0]).Error; err != nil {
+		t.Fatalf("error deleting name: %v", err)
+	}
+
+	ids := make([]uint, 0, total+1)
+	for _, n := range names {
+		ids = append(ids, n.ID)
+	}
+	ids = append(ids, uint(total+100))
+
+	got, err := models.DBNameSource{}.NamesByID(ids)
+	if err != nil {
+		t.Fatalf("NamesByID() error: %v", err)
+	}
+	if len(got) != total {
+		t.Fatalf("NamesByID() returned %d names, want %d", len(got), total)
+	}
+	seen := make(map[uint]bool, len(got))
+	for _, n := range got {
+		seen[n.ID] = true
+	}
+	for _, n := range names {
+		if !seen[n.ID] {
+			t.Fatalf("NamesByID() missed the name %d", n.ID)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// NameIndex is the in-memory index of the cached name types. It must be treated as read only, so it can be shared by
+// concurrent requests. Changes are made with Apply, which returns a new index.
+type NameIndex struct {
+	byID        *cowMap[uint, NameType]
+	byName      *cowMap[string, uint]
+	byCode      map[string]*cowMap[string, []uint]
+	byVariation *cowMap[string, []uint]
+	codes       map[string]*bkTree
+}
+
+// NewNameIndex builds the index of the given name types. Missing phonetic codes are computed.
+func NewNameIndex(names []NameType) *NameIndex {
+	idx := &NameIndex{
+		byID:        newCowMap[uint, NameType](hashID),
+		byName:      newCowMap[string, uint](hashString),
+		byCode:      make(map[string]*cowMap[string, []uint], len(Encoders)),
+		byVariation: newCowMap[string, []uint](hashString),
+		codes:       make(map[string]*bkTree, len(Encoders)),
+	}
+	for algorithm := range Encoders {
+		idx.byCode[algorithm] = newCowMap[string, []uint](hashString)
+		idx.codes[algorithm] = &bkTree{}
+	}
+
+	// The index is not shared yet, so the id slices and the bk-trees are modified in place
+	for _, n := range names {
+		n.EncodeName()
+
+		idx.byID.set(n.ID, n)
+		idx.byName.set(n.Name, n.ID)
+		for algorithm := range Encoders {
+			code := n.Code(algorithm)
+			ids, _ := idx.byCode[algorithm].get(code)
+			idx.byCode[algorithm].set(code, append(ids, n.ID))
+			idx.codes[algorithm].Add(code)
+		}
+
+		for _, variation := range SplitVariations(n.NameVariations) {
+			ids, _ := idx.byVariation.get(variation)
+			idx.byVariation.set(variation, append(ids, n.ID))
+		}
+	}
+
+	return idx
+}
+
+// Apply returns a copy of the index with the given name types inserted or replaced and the name types with the given
+// ids removed. The index itself is left untouched so readers of it are never blocked: only the shards, id slices and
+// bk-tree paths that change are copied, the rest is shared.
+func (idx *NameIndex) Apply(upserts []NameType, deletes []uint) *NameIndex {
+	next := &NameIndex{
+		byID:        idx.byID.clone(),
+		byName:      idx.byName.clone(),
+		byCode:      make(map[string]*cowMap[string, []uint], len(idx.byCode)),
+		byVariation: idx.byVariation.clone(),
+		codes:       make(map[string]*bkTree, len(idx.codes)),
+	}
+	for algorithm, codes := range idx.byCode {
+		next.byCode[algorithm] = codes.clone()
+	}
+	for algorithm, tree := range idx.codes {
+		next.codes[algorithm] = tree
+	}
+
+	for _, id := range deletes {
+		next.remove(id)
+	}
+	for _, n := range upserts {
+		next.remove(n.ID)
+		next.insert(n)
+	}
+
+	return next
+}
+
+// remove removes the name type with the given id. The id slices are copied, never modified in place.
+func (idx *NameIndex) remove(id uint) {
+	n, ok := idx.byID.get(id)
+	if !ok {
+		return
+	}
+
+	idx.byID.remove(id)
+	if current, _ := idx.byName.get(n.Name); current == id {
+		idx.byName.remove(n.Name)
+	}
+	for algorithm := range Encoders {
+		// the code stays on the bk-tree, searches skip codes without names
+		removeID(idx.byCode[algorithm], n.Code(algorithm), id)
+	}
+	for _, variation := range SplitVariations(n.NameVariations) {
+		removeID(idx.byVariation, variation, id)
+	}
+}
+
+// insert adds the name type keeping the id slices in ascending order. The id slices and the bk-trees are copied,
+// never modified in place.
+func (idx *NameIndex) insert(n NameType) {
+	n.EncodeName()
+
+	idx.byID.set(n.ID, n)
+	idx.byName.set(n.Name, n.ID)
+	for algorithm := range Encoders {
+		code := n.Code(algorithm)
+		ids, ok := idx.byCode[algorithm].get(code)
+		if !ok {
+			idx.codes[algorithm] = idx.codes[algorithm].With(code)
+		}
+		idx.byCode[algorithm].set(code, withID(ids, n.ID))
+	}
+	for _, variation := range SplitVariations(n.NameVariations) {
+		ids, _ := idx.byVariation.get(variation)
+		idx.byVariation.set(variation, withID(ids, n.ID))
+	}
+}
+
+// removeID removes id from the ids of the key, removing the key when no id is left
+func removeID(m *cowMap[string, []uint], key string, id uint) {
+	ids, ok := m.get(key)
+	if !ok {
+		return
+	}
+
+	next := make([]uint, 0, len(ids))
+	for _, i := range ids {
+		if i != id {
+			next = append(next, i)
+		}
+	}
+
+	if len(next) == 0 {
+		m.remove(key)
+		return
+	}
+	m.set(key, next)
+}
+
+// withID returns a copy of the ascending ids with id inserted in order
+func withID(ids []uint, id uint) []uint {
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+	if i < len(ids) && ids[i] == id {
+		return ids
+	}
+
+	next := make([]uint, 0, len(ids)+1)
+	next = append(next, ids[:i]...)
+	next = append(next, id)
+	return append(next, ids[i:]...)
+}
+
+// Len returns the number of name types on the index
+func (idx *NameIndex) Len() int {
+	return idx.byID.len()
+}
+
+// ByID returns the name type with the given id
+func (idx *NameIndex) ByID(id uint) (NameType, bool) {
+	return idx.byID.get(id)
+}
+
+// ByName returns the name type with the given name
+func (idx *NameIndex) ByName(name string) (NameType, bool) {
+	id, ok := idx.byName.get(name)
+	if !ok {
+		return NameType{}, false
+	}
+	return idx.byID.get(id)
+}
+
+// ByMetaphone returns every name type with the given metaphone-br code
+func (idx *NameIndex) ByMetaphone(code string) []NameType {
+	return idx.ByCode(AlgorithmMetaphoneBR, code)
+}
+
+// ByCode returns every name type with the given code of the given phonetic algorithm
+func (idx *NameIndex) ByCode(algorithm, code string) []NameType {
+	codes, ok := idx.byCode[algorithm]
+	if !ok {
+		return nil
+	}
+	ids, _ := codes.get(code)
+	return idx.lookup(ids)
+}
+
+// ByVariation returns every name type that lists the given name as a variation
+func (idx *NameIndex) ByVariation(variation string) []NameType {
+	ids, _ := idx.byVariation.get(variation)
+	return idx.lookup(ids)
+}
+
+// BySimilarMetaphone returns every name type with a metaphone-br code similar to the given one,
+// as defined by metaphone.IsMetaphoneSimilar.
+func (idx *NameIndex) BySimilarMetaphone(code string) []NameType {
+	return idx.BySimilarCode(AlgorithmMetaphoneBR, code)
+}
+
+// BySimilarCode returns every name type with a code of the given phonetic algorithm similar to the given one,
+// as defined by the IsSimilar of the algorithm encoder.
+func (idx *NameIndex) BySimilarCode(algorithm, code string) []NameType {
+	encoder, err := GetEncoder(algorithm)
+	if err != nil {
+		return nil
+	}
+
+	// The max distance of the encoder bounds the search radius and every code found is checked again
+	var ids []uint
+	for _, c := range idx.codes[algorithm].Search(code, encoder.MaxDistance(code)) {
+		if encoder.IsSimilar(code, c) {
+			codeIDs, _ := idx.byCode[algorithm].get(c)
+			ids = append(ids, codeIDs...)
+		}
+	}
+
+	// keep the table order, as a linear scan would
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return idx.lookup(ids)
+}
+
+// lookup returns the name types of the given ids
+func (idx *NameIndex) lookup(ids []uint) []NameType {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	names := make([]NameType, 0, len(ids))
+	for _, id := range ids {
+		if n, ok := idx.byID.get(id); ok {
+			names = append(names, n)
+		}
+	}
+
+	return names
+}
+
+// SplitVariations splits the pipe delimited NameVariations string into uppercase variations
+func SplitVariations(nameVariations string) []string {
+	var variations []string
+	for _, v := range strings.Split(nameVariations, "|") {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if v != "" {
+			variations = append(variations, v)
+		}
+	}
+	return variations
+}
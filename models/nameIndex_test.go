@@ -0,0 +1,95 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Darklabel91/metaphone-br"
+)
+
+// scanNames returns the ids of the names matching keep, in table order, as the linear scans did
+func scanNames(names []NameType, keep func(NameType) bool) []uint {
+	var ids []uint
+	for _, n := range names {
+		if keep(n) {
+			ids = append(ids, n.ID)
+		}
+	}
+	return ids
+}
+
+func idsOf(names []NameType) []uint {
+	var ids []uint
+	for _, n := range names {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+func TestNameIndex(t *testing.T) {
+	checkIndex(t, testIndex(), testNames())
+}
+
+func TestNameIndexApply(t *testing.T) {
+	idx := testIndex()
+
+	// Rename MARIA, change the variations of ANA, remove PEDRO and SILVA and add CARLOS
+	names := testNames()
+	names[5].Name, names[5].Metaphone = "MARIAH", ""
+	names[0].NameVariations = "|ANA|ANNA|AINA|"
+	carlos := NameType{Name: "CARLOS", Classification: "M", NameVariations: "|CARLOS|KARLOS|"}
+	carlos.ID = 20
+	applied := idx.Apply([]NameType{names[5], names[0], carlos}, []uint{names[7].ID, names[9].ID, 99})
+
+	want := append([]NameType{}, names[:7]...)
+	want = append(want, names[8], names[10], carlos)
+	for i := range want {
+		want[i].EncodeName()
+	}
+	checkIndex(t, applied, want)
+
+	// The original index is left untouched
+	checkIndex(t, idx, testNames())
+}
+
+// checkIndex compares the lookups of the index with linear scans of the names, querying every name and variation
+// of the test names and the given ones and a few names that are on neither
+func checkIndex(t *testing.T, idx *NameIndex, names []NameType) {
+	t.Helper()
+	if idx.Len() != len(names) {
+		t.Fatalf("Len() = %d, want %d", idx.Len(), len(names))
+	}
+
+	queries := []string{"XYZZY", "MARINA", "RAFAELA", "LU", ""}
+	for _, n := range append(testNames(), names...) {
+		queries = append(queries, n.Name)
+		queries = append(queries, SplitVariations(n.NameVariations)...)
+	}
+
+	for _, q := range queries {
+		code := metaphone.Pack(q)
+		if got, want := idsOf(idx.ByMetaphone(code)), scanNames(names, func(n NameType) bool { return n.Metaphone == code }); !reflect.DeepEqual(got, want) {
+			t.Errorf("ByMetaphone(%q) = %v, want %v", code, got, want)
+		}
+		if got, want := idsOf(idx.BySimilarMetaphone(code)), scanNames(names, func(n NameType) bool { return metaphone.IsMetaphoneSimilar(code, n.Metaphone) }); !reflect.DeepEqual(got, want) {
+			t.Errorf("BySimilarMetaphone(%q) = %v, want %v", code, got, want)
+		}
+		if got, want := idsOf(idx.ByVariation(q)), scanNames(names, func(n NameType) bool { return contains(SplitVariations(n.NameVariations), q) }); !reflect.DeepEqual(got, want) {
+			t.Errorf("ByVariation(%q) = %v, want %v", q, got, want)
+		}
+		n, ok := idx.ByName(q)
+		if want := scanNames(names, func(n NameType) bool { return n.Name == q }); ok != (len(want) == 1) || ok && n.ID != want[0] {
+			t.Errorf("ByName(%q) = %d, %v, want %v", q, n.ID, ok, want)
+		}
+	}
+}
+
+func TestSplitVariations(t *testing.T) {
+	got := SplitVariations("|ana| Hanna ||ANNA|")
+	if want := []string{"ANA", "HANNA", "ANNA"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("SplitVariations = %q, want %q", got, want)
+	}
+	if got := SplitVariations(""); len(got) != 0 {
+		t.Fatalf("SplitVariations(\"\") = %q, want none", got)
+	}
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultListLimit is the number of names of a page when no limit is given
+const DefaultListLimit = 50
+
+// MaxListLimit is the maximum number of names of a page
+const MaxListLimit = 1000
+
+// ListSorts maps the sort fields of a listing to their columns
+var ListSorts = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// ListOptions filters, sorts and paginates a listing of the names. Empty filters match every name.
+type ListOptions struct {
+	Limit int
+	// Sort is a key of ListSorts, the names are sorted by id when it is empty and Desc reverses the order
+	Sort string
+	Desc bool
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+
+	Classification string
+	Metaphone      string
+	// Prefix matches the names starting with it
+	Prefix string
+	// The After times are inclusive and the Before times are exclusive
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	UpdatedAfter   *time.Time
+	UpdatedBefore  *time.Time
+	IncludeDeleted bool
+}
+
+// NamePage is a page of a listing of the names
+type NamePage struct {
+	Names []NameType
+	// Total is the number of names that match the filters, on every page
+	Total int64
+	// NextCursor is the cursor of the next page, empty on the last page
+	NextCursor string `json:"NextCursor,omitempty"`
+}
+
+// ErrInvalidCursor is returned when the cursor of a listing can't be parsed or belongs to a listing with another sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// listCursor is the position of a listing after the last name of a page: the value of the sort field and the id of
+// the name, which breaks the ties
+type listCursor struct {
+	Sort  string
+	Desc  bool
+	Value string `json:"Value,omitempty"`
+	ID    uint
+}
+
+// ListNames returns a page of the names that match the options, ordered by the sort field and the id. The pages are
+// keyed by the last name of the previous one, so names written between two requests don't shift them.
+func ListNames(opts ListOptions) (NamePage, error) {
+	if opts.Sort == "" {
+		opts.Sort = "id"
+	}
+	column, ok := ListSorts[opts.Sort]
+	if !ok {
+		return NamePage{}, fmt.Errorf("invalid sort %q: %w", opts.Sort, errors.New("sort must be id, name, created_at or updated_at"))
+	}
+	if opts.Limit < 1 || opts.Limit > MaxListLimit {
+		opts.Limit = DefaultListLimit
+	}
+
+	query := listQuery(opts)
+
+	var page NamePage
+	err := query.Session(&gorm.Session{}).Count(&page.Total).Error
+	if err != nil {
+		return NamePage{}, fmt.Errorf("error counting names: %w", err)
+	}
+
+	// Start after the cursor
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor, opts.Sort, opts.Desc)
+		if err != nil {
+			return NamePage{}, err
+		}
+		query, err = afterCursor(query, column, cursor)
+		if err != nil {
+			return NamePage{}, err
+		}
+	}
+
+	// Read one more name than the limit to know if there is a next page
+	order := " ASC"
+	if opts.Desc {
+		order = " DESC"
+	}
+	if column != "id" {
+		query = query.Order(column + order)
+	}
+	err = query.Order("id" + order).Limit(opts.Limit + 1).Find(&page.Names).Error
+	if err != nil {
+		return NamePage{}, fmt.Errorf("error listing names: %w", err)
+	}
+
+	if len(page.Names) > opts.Limit {
+		page.Names = page.Names[:opts.Limit]
+		page.NextCursor = encodeCursor(page.Names[opts.Limit-1], opts.Sort, opts.Desc)
+	}
+	if page.Names == nil {
+		page.Names = []NameType{}
+	}
+	return page, nil
+}
+
+// listQuery returns the query of the names that match the filters of the options
+func listQuery(opts ListOptions) *gorm.DB {
+	query := DB.Model(&NameType{})
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if opts.Classification != "" {
+		query = query.Where("classification = ?", opts.Classification)
+	}
+	if opts.Metaphone != "" {
+		query = query.Where("metaphone = ?", opts.Metaphone)
+	}
+	if opts.Prefix != "" {
+		query = query.Where("name LIKE ?", opts.Prefix+"%")
+	}
+	if opts.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *opts.CreatedAfter)
+	}
+	if opts.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *opts.CreatedBefore)
+	}
+	if opts.UpdatedAfter != nil {
+		query = query.Where("updated_at >= ?", *opts.UpdatedAfter)
+	}
+	if opts.UpdatedBefore != nil {
+		query = query.Where("updated_at < ?", *opts.UpdatedBefore)
+	}
+	return query
+}
+
+// afterCursor restricts the query to the names after the cursor on the order of the column
+func afterCursor(query *gorm.DB, column string, cursor listCursor) (*gorm.DB, error) {
+	op := ">"
+	if cursor.Desc {
+		op = "<"
+	}
+	if column == "id" {
+		return query.Where("id "+op+" ?", cursor.ID), nil
+	}
+
+	var value interface{} = cursor.Value
+	if column != "name" {
+		t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		value = t
+	}
+	return query.Where("("+column+" "+op+" ? OR ("+column+" = ? AND id "+op+" ?))", value, value, cursor.ID), nil
+}
+
+// encodeCursor returns the cursor of the listing after the given name
+func encodeCursor(n NameType, sort string, desc bool) string {
+	cursor := listCursor{Sort: sort, Desc: desc, ID: n.ID}
+	switch sort {
+	case "name":
+		cursor.Value = n.Name
+	case "created_at":
+		cursor.Value = n.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = n.UpdatedAt.Format(time.RFC3339Nano)
+	}
+
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a cursor, which must have been returned by a listing with the same sort
+func decodeCursor(s string, sort string, desc bool) (listCursor, error) {
+	var cursor listCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, &cursor)
+	}
+	if err != nil {
+		return listCursor{}, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Desc != desc {
+		return listCursor{}, fmt.Errorf("%w: it doesn't match the sort of the listing", ErrInvalidCursor)
+	}
+	return cursor, nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestListCursor(t *testing.T) {
+	n := NameType{Name: "MARIA"}
+	n.ID = 42
+	n.CreatedAt = time.Date(2023, 4, 12, 18, 48, 53, 123456789, time.UTC)
+	n.UpdatedAt = n.CreatedAt.Add(time.Hour)
+
+	tests := []struct {
+		sort  string
+		desc  bool
+		value string
+	}{
+		{"id", false, ""},
+		{"id", true, ""},
+		{"name", false, "MARIA"},
+		{"created_at", true, "2023-04-12T18:48:53.123456789Z"},
+		{"updated_at", false, "2023-04-12T19:48:53.123456789Z"},
+	}
+	for _, tt := range tests {
+		s := encodeCursor(n, tt.sort, tt.desc)
+		cursor, err := decodeCursor(s, tt.sort, tt.desc)
+		if err != nil {
+			t.Errorf("decodeCursor(encodeCursor(%s, %v)) error: %v", tt.sort, tt.desc, err)
+			continue
+		}
+		if want := (listCursor{Sort: tt.sort, Desc: tt.desc, Value: tt.value, ID: 42}); cursor != want {
+			t.Errorf("cursor of %s, %v = %+v, want %+v", tt.sort, tt.desc, cursor, want)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	n := NameType{Name: "MARIA"}
+	n.ID = 42
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "%%%"},
+		{"not json", "bm90IGpzb24"},
+		{"another sort", encodeCursor(n, "id", false)},
+		{"another order", encodeCursor(n, "name", true)},
+	}
+	for _, tt := range tests {
+		if _, err := decodeCursor(tt.cursor, "name", false); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: decodeCursor error = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
@@ -68,3 +68,50 @@ func contains(s []string, str string) bool {
 
 	return false
 }
+
+// MaxTopCandidates is the maximum number of candidates returned on a ranked search
+const MaxTopCandidates = 100
+
+// Candidate match types ordered by strength
+const (
+	MatchExact            = "exact"
+	MatchVariation        = "variation"
+	MatchMetaphone        = "metaphone"
+	MatchSimilarMetaphone = "similar_metaphone"
+)
+
+// matchTypeRank is used to break ties between candidates with the same similarity
+var matchTypeRank = map[string]int{
+	MatchExact:            0,
+	MatchVariation:        1,
+	MatchMetaphone:        2,
+	MatchSimilarMetaphone: 3,
+}
+
+// Candidate is a scored NameType returned by a ranked search
+type Candidate struct {
+	Name           string
+	Classification string
+	Metaphone      string // code of the name on the phonetic algorithm of the search
+	Similarity     float32
+	MatchType      string
+}
+
+// OrderCandidates sorts candidates by descending similarity, then by match type strength and then by ascending name length.
+func OrderCandidates(arr []Candidate) []Candidate {
+	// create a copy of the original array
+	sortedArr := make([]Candidate, len(arr))
+	copy(sortedArr, arr)
+
+	sort.SliceStable(sortedArr, func(i, j int) bool {
+		if sortedArr[i].Similarity != sortedArr[j].Similarity {
+			return sortedArr[i].Similarity > sortedArr[j].Similarity
+		}
+		if matchTypeRank[sortedArr[i].MatchType] != matchTypeRank[sortedArr[j].MatchType] {
+			return matchTypeRank[sortedArr[i].MatchType] < matchTypeRank[sortedArr[j].MatchType]
+		}
+		return len(sortedArr[i].Name) < len(sortedArr[j].Name)
+	})
+
+	return sortedArr
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Darklabel91/metaphone-br"
+)
+
+func TestOrderCandidates(t *testing.T) {
+	candidates := []Candidate{
+		{Name: "MARIANA", Similarity: 0.8, MatchType: MatchSimilarMetaphone},
+		{Name: "MARIA", Similarity: 0.8, MatchType: MatchSimilarMetaphone},
+		{Name: "MARIAH", Similarity: 0.8, MatchType: MatchMetaphone},
+		{Name: "MARYA", Similarity: 0.9, MatchType: MatchVariation},
+		{Name: "MARIO", Similarity: 0.8, MatchType: MatchSimilarMetaphone},
+		{Name: "MARIA", Similarity: 1, MatchType: MatchExact},
+		{Name: "MARINA", Similarity: 0.8, MatchType: MatchSimilarMetaphone},
+	}
+	input := make([]Candidate, len(candidates))
+	copy(input, candidates)
+
+	// Ties on similarity go to the strongest match type, then to the shortest name, then keep their order
+	want := []string{"MARIA", "MARYA", "MARIAH", "MARIA", "MARIO", "MARINA", "MARIANA"}
+	var got []string
+	for _, c := range OrderCandidates(candidates) {
+		got = append(got, c.Name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("OrderCandidates = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(candidates, input) {
+		t.Fatal("OrderCandidates changed its input")
+	}
+	if ordered := OrderCandidates(nil); len(ordered) != 0 {
+		t.Fatalf("OrderCandidates(nil) = %v, want none", ordered)
+	}
+}
+
+func TestGetSimilarCandidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		first     string
+		matchType string
+	}{
+		{"Mariana", "MARIANA", MatchExact},
+		{"hanna", "ANA", MatchVariation},
+		{"RAPHAEL", "RAFAEL", MatchVariation},
+		{"Thiago", "TIAGO", MatchVariation},
+		{"Pedru", "PEDRO", MatchMetaphone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := GetSimilarCandidates(tt.name, testIndex(), MaxTopCandidates, DefaultAlgorithm)
+			if err != nil {
+				t.Fatalf("error getting candidates: %v", err)
+			}
+			if all[0].Name != tt.first || all[0].MatchType != tt.matchType {
+				t.Fatalf("first candidate = %s %s, want %s %s", all[0].Name, all[0].MatchType, tt.first, tt.matchType)
+			}
+
+			seen := make(map[string]bool)
+			for i, c := range all {
+				if seen[c.Name] {
+					t.Errorf("%s is listed twice", c.Name)
+				}
+				seen[c.Name] = true
+				if want := metaphone.SimilarityBetweenWords(strings.ToLower(tt.name), strings.ToLower(c.Name)); c.Similarity != want {
+					t.Errorf("%s has similarity %v, want %v", c.Name, c.Similarity, want)
+				}
+				if i > 0 && c.Similarity > all[i-1].Similarity {
+					t.Errorf("%s is ranked below %s with a higher similarity", c.Name, all[i-1].Name)
+				}
+			}
+
+			// The top candidates are the first ones of the full ranking
+			top, err := GetSimilarCandidates(tt.name, testIndex(), 1, DefaultAlgorithm)
+			if err != nil || len(top) != 1 || !reflect.DeepEqual(top[0], all[0]) {
+				t.Fatalf("top 1 = %+v, %v, want %+v", top, err, all[0])
+			}
+		})
+	}
+
+	if _, err := GetSimilarCandidates("Xyzzy", testIndex(), MaxTopCandidates, DefaultAlgorithm); err == nil {
+		t.Fatal("GetSimilarCandidates(Xyzzy) found candidates, want an error")
+	}
+	if _, err := GetSimilarCandidates("Maria", testIndex(), MaxTopCandidates, "nysiis"); err == nil {
+		t.Fatal("GetSimilarCandidates with an unknown algorithm found candidates, want an error")
+	}
+}
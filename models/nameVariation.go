@@ -0,0 +1,177 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Sources of a name variation
+const (
+	SourceCSV    = "csv"
+	SourceAPI    = "api"
+	SourceImport = "import"
+)
+
+// NameVariation is a struct representing a single variation of a canonical name
+type NameVariation struct {
+	ID         uint   `gorm:"primarykey"`
+	NameTypeID uint   `gorm:"not null;uniqueIndex:idx_name_variation"`
+	Variation  string `gorm:"size:255;not null;index;uniqueIndex:idx_name_variation"`
+	Source     string `gorm:"size:64"`
+	CreatedAt  time.Time
+}
+
+// VariationsInput is the struct for the add and delete variations body
+type VariationsInput struct {
+	Variations []string `json:"Variations"`
+	Source     string   `json:"Source,omitempty"`
+}
+
+// NormalizeVariations uppercases and trims the given variations, removing duplicates.
+// It returns an error if a variation is empty or contains anything but letters.
+func NormalizeVariations(variations []string) ([]string, error) {
+	seen := make(map[string]bool, len(variations))
+	var normalized []string
+	for _, v := range variations {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if v == "" {
+			return nil, errors.New("variation must not be empty")
+		}
+		for _, r := range v {
+			if !unicode.IsLetter(r) {
+				return nil, fmt.Errorf("variation %q must contain only letters", v)
+			}
+		}
+		if !seen[v] {
+			seen[v] = true
+			normalized = append(normalized, v)
+		}
+	}
+	return normalized, nil
+}
+
+// JoinVariations joins the given variations into the pipe delimited NameVariations string, sorted and without duplicates
+func JoinVariations(variations []string) string {
+	if len(variations) == 0 {
+		return ""
+	}
+
+	sorted := make([]string, len(variations))
+	copy(sorted, variations)
+	sort.Strings(sorted)
+
+	var joined []string
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			joined = append(joined, v)
+		}
+	}
+
+	return "|" + strings.Join(joined, "|") + "|"
+}
+
+// GetVariations returns all variations of the name with the given ID
+func GetVariations(nameTypeID uint) ([]NameVariation, error) {
+	var variations []NameVariation
+	err := DB.Where("name_type_id = ?", nameTypeID).Order("variation").Find(&variations).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting variations: %w", err)
+	}
+	return variations, nil
+}
+
+// AddVariations adds the given variations to the name, ignoring the ones it already has
+func (n *NameType) AddVariations(variations []string, source string) (NameType, error) {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, v := range variations {
+			nv := NameVariation{NameTypeID: n.ID, Variation: v, Source: source}
+			err := tx.Where(NameVariation{NameTypeID: n.ID, Variation: v}).FirstOrCreate(&nv).Error
+			if err != nil {
+				return fmt.Errorf("error adding variation %q: %w", v, err)
+			}
+		}
+		if err := n.syncVariations(tx); err != nil {
+			return err
+		}
+		return recordChange(tx, n.ID, ChangeUpsert)
+	})
+	if err != nil {
+		return NameType{}, err
+	}
+	return *n, nil
+}
+
+// DeleteVariations removes the given variations from the name
+func (n *NameType) DeleteVariations(variations []string) (NameType, error) {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("name_type_id = ? AND variation IN ?", n.ID, variations).Delete(&NameVariation{})
+		if result.Error != nil {
+			return fmt.Errorf("error deleting variations: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("variations not found on the name")
+		}
+		if err := n.syncVariations(tx); err != nil {
+			return err
+		}
+		return recordChange(tx, n.ID, ChangeUpsert)
+	})
+	if err != nil {
+		return NameType{}, err
+	}
+	return *n, nil
+}
+
+// ReplaceVariations replaces all variations of the name by the ones on the NameVariations string, in the given transaction
+func (n *NameType) ReplaceVariations(tx *gorm.DB, source string) error {
+	err := tx.Where("name_type_id = ?", n.ID).Delete(&NameVariation{}).Error
+	if err != nil {
+		return fmt.Errorf("error deleting variations: %w", err)
+	}
+
+	variations := VariationsOf(*n, source)
+	if len(variations) == 0 {
+		return nil
+	}
+
+	err = tx.Create(&variations).Error
+	if err != nil {
+		return fmt.Errorf("error creating variations: %w", err)
+	}
+	return nil
+}
+
+// syncVariations rebuilds the NameVariations string of the name from the name_variations table
+func (n *NameType) syncVariations(tx *gorm.DB) error {
+	var variations []string
+	err := tx.Model(&NameVariation{}).Where("name_type_id = ?", n.ID).Pluck("variation", &variations).Error
+	if err != nil {
+		return fmt.Errorf("error getting variations: %w", err)
+	}
+
+	n.NameVariations = JoinVariations(variations)
+	err = tx.Model(n).Update("name_variations", n.NameVariations).Error
+	if err != nil {
+		return fmt.Errorf("error updating name variations: %w", err)
+	}
+	return nil
+}
+
+// VariationsOf splits the NameVariations string of the given name into NameVariation rows
+func VariationsOf(n NameType, source string) []NameVariation {
+	var variations []NameVariation
+	seen := make(map[string]bool)
+	for _, v := range SplitVariations(n.NameVariations) {
+		if !seen[v] {
+			seen[v] = true
+			variations = append(variations, NameVariation{NameTypeID: n.ID, Variation: v, Source: source})
+		}
+	}
+	return variations
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Roles of a user. Every role is granted the permissions of the roles below it: readers look names up, editors also
+// change the dictionary and admins also manage the service and the roles of the users.
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// DefaultRole is the role of the users created by signup
+const DefaultRole = RoleReader
+
+// roleRanks orders the roles by their permissions
+var roleRanks = map[string]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// RoleInput is the struct for the grant role body
+type RoleInput struct {
+	Role string `json:"Role"`
+}
+
+// UserRole is a user with its role, without its credentials
+type UserRole struct {
+	ID    uint
+	Email string
+	Role  string
+}
+
+// ParseRole returns the role of the given value, in any case
+func ParseRole(value string) (string, error) {
+	role := strings.ToLower(strings.TrimSpace(value))
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("invalid role %q: %w", value, errors.New("role must be admin, editor or reader"))
+	}
+	return role, nil
+}
+
+// RoleAllows reports whether the given role has the permissions of the required one. Unknown roles have none.
+func RoleAllows(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+// SetRole saves the role of the user
+func (u *User) SetRole(role string) error {
+	err := DB.Model(u).Update("role", role).Error
+	if err != nil {
+		return fmt.Errorf("error updating user role: %w", err)
+	}
+	return nil
+}
+
+// GetUserRoles returns the role of every user, ordered by id
+func GetUserRoles() ([]UserRole, error) {
+	var roles []UserRole
+	err := DB.Model(&User{}).Select("id", "email", "role").Order("id").Find(&roles).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting user roles: %w", err)
+	}
+	return roles, nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, required string
+		want           bool
+	}{
+		{RoleReader, RoleReader, true},
+		{RoleReader, RoleEditor, false},
+		{RoleReader, RoleAdmin, false},
+		{RoleEditor, RoleReader, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleEditor, RoleAdmin, false},
+		{RoleAdmin, RoleReader, true},
+		{RoleAdmin, RoleEditor, true},
+		{RoleAdmin, RoleAdmin, true},
+		{"", RoleReader, false},
+		{"root", RoleReader, false},
+		{"Admin", RoleAdmin, false},
+	}
+	for _, tt := range tests {
+		if got := RoleAllows(tt.role, tt.required); got != tt.want {
+			t.Errorf("RoleAllows(%q, %q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"reader", RoleReader, false},
+		{"Editor", RoleEditor, false},
+		{" ADMIN ", RoleAdmin, false},
+		{"", "", true},
+		{"root", "", true},
+		{"superadmin", "", true},
+		{"read", "", true},
+		{"admin,editor", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRole(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRole(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
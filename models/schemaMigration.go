@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaMigration records a migration of the database schema applied to the database, see database.Migrations
+type SchemaMigration struct {
+	Version   uint   `gorm:"primarykey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Seed records a CSV file fully imported into the database, so it is not imported again
+type Seed struct {
+	ID        uint   `gorm:"primarykey"`
+	File      string `gorm:"size:255;uniqueIndex"`
+	Rows      int
+	CreatedAt time.Time
+}
@@ -0,0 +1,243 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token doesn't exist, is expired or is revoked
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when a refresh token already rotated is used again. Its session is revoked, as
+	// the token may have been stolen.
+	ErrRefreshTokenReused = errors.New("refresh token reused, session revoked")
+)
+
+// RefreshToken is a refresh token of a session. A session starts on login and every refresh rotates its refresh token:
+// the used token is spent and a new one is issued with a new access token. Only the hash of the token is stored.
+type RefreshToken struct {
+	ID     uint `gorm:"primarykey"`
+	UserID uint `gorm:"not null;index"`
+	// SessionID is shared by the refresh tokens rotated from the same login
+	SessionID string `gorm:"size:64;not null;index"`
+	Hash      string `gorm:"size:64;not null;uniqueIndex"`
+	// AccessJTI is the id of the access token issued with the refresh token, revoked with its session
+	AccessJTI       string    `gorm:"size:64;not null"`
+	AccessExpiresAt time.Time `gorm:"not null"`
+	ExpiresAt       time.Time `gorm:"not null;index"`
+	UsedAt          *time.Time
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+// RevokedToken is an access token revoked before its expiration, by the id on its jti claim. It is kept until the
+// token expires.
+type RevokedToken struct {
+	JTI       string    `gorm:"primarykey;size:64"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// RefreshTokenInput is the struct for the refresh token body, for clients without cookies
+type RefreshTokenInput struct {
+	RefreshToken string `json:"RefreshToken"`
+}
+
+// NewTokenID returns a random id for the jti claim of an access token or a session
+func NewTokenID() (string, error) {
+	return randomToken(16)
+}
+
+// CreateRefreshToken creates a refresh token of the user for the session with the given id, issued with the access
+// token with the given id and expiration. It returns the token, which is not stored.
+func (u *User) CreateRefreshToken(sessionID, accessJTI string, accessExpiresAt time.Time, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("error generating refresh token: %w", err)
+	}
+
+	err = DB.Create(&RefreshToken{
+		UserID:          u.ID,
+		SessionID:       sessionID,
+		Hash:            hashToken(token),
+		AccessJTI:       accessJTI,
+		AccessExpiresAt: accessExpiresAt,
+		ExpiresAt:       time.Now().Add(ttl),
+	}).Error
+	if err != nil {
+		return "", fmt.Errorf("error creating refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// GetRefreshToken returns the refresh token matching the given token, spent or revoked ones included
+func GetRefreshToken(token string) (RefreshToken, error) {
+	var rt RefreshToken
+	err := DB.Where("hash = ?", hashToken(token)).Limit(1).Find(&rt).Error
+	if err != nil {
+		return RefreshToken{}, fmt.Errorf("error getting refresh token: %w", err)
+	}
+	if rt.ID == 0 {
+		return RefreshToken{}, ErrInvalidRefreshToken
+	}
+	return rt, nil
+}
+
+// SpendRefreshToken marks the refresh token matching the given token as used and returns it with its user, so the
+// caller issues the next tokens of its session. A token already used revokes its session and returns
+// ErrRefreshTokenReused.
+func SpendRefreshToken(token string) (RefreshToken, User, error) {
+	rt, err := GetRefreshToken(token)
+	if err != nil {
+		return RefreshToken{}, User{}, err
+	}
+	if rt.RevokedAt != nil || time.Now().After(rt.ExpiresAt) {
+		return RefreshToken{}, User{}, ErrInvalidRefreshToken
+	}
+
+	// Only one request spends the token, a concurrent one is a reuse
+	res := DB.Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL", rt.ID).Update("used_at", time.Now())
+	if res.Error != nil {
+		return RefreshToken{}, User{}, fmt.Errorf("error spending refresh token: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		if err := RevokeSession(rt.SessionID); err != nil {
+			return RefreshToken{}, User{}, err
+		}
+		return RefreshToken{}, User{}, ErrRefreshTokenReused
+	}
+
+	u, err := GetUserByID(rt.UserID)
+	if err != nil {
+		return RefreshToken{}, User{}, ErrInvalidRefreshToken
+	}
+	return rt, u, nil
+}
+
+// RevokeSession revokes the refresh tokens of the session with the given id and the access tokens issued with them
+func RevokeSession(sessionID string) error {
+	_, err := revokeRefreshTokens("session_id", sessionID)
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	return nil
+}
+
+// RevokeSessions revokes every session of the user, and returns the number of sessions revoked
+func (u *User) RevokeSessions() (int, error) {
+	n, err := revokeRefreshTokens("user_id", u.ID)
+	if err != nil {
+		return 0, fmt.Errorf("error revoking sessions: %w", err)
+	}
+	return n, nil
+}
+
+// revokeRefreshTokens revokes the refresh tokens with the given value on column and adds the access tokens issued with them that are
+// not expired yet to the revoked tokens. It returns the number of sessions with a refresh token revoked.
+func revokeRefreshTokens(column string, value interface{}) (int, error) {
+	now := time.Now()
+	sessions := make(map[string]bool)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var tokens []RefreshToken
+		err := tx.Where(column+" = ? AND (revoked_at IS NULL OR access_expires_at > ?)", value, now).Find(&tokens).Error
+		if err != nil {
+			return err
+		}
+
+		var ids []uint
+		var revoked []RevokedToken
+		for _, rt := range tokens {
+			if rt.RevokedAt == nil {
+				ids = append(ids, rt.ID)
+				sessions[rt.SessionID] = true
+			}
+			if rt.AccessExpiresAt.After(now) {
+				revoked = append(revoked, RevokedToken{JTI: rt.AccessJTI, UserID: rt.UserID, ExpiresAt: rt.AccessExpiresAt})
+			}
+		}
+
+		if len(ids) != 0 {
+			err = tx.Model(&RefreshToken{}).Where("id IN ?", ids).Update("revoked_at", now).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(revoked) != 0 {
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(sessions), nil
+}
+
+// RevokeToken adds the access token with the given id and expiration to the revoked tokens
+func RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}).Error
+	if err != nil {
+		return fmt.Errorf("error revoking token: %w", err)
+	}
+	return nil
+}
+
+// IsTokenRevoked reports whether the access token with the given id is revoked
+func IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := DB.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error checking revoked token: %w", err)
+	}
+	return count != 0, nil
+}
+
+// PruneSessions deletes the refresh tokens and the revoked tokens expired before the given time
+func PruneSessions(before time.Time) error {
+	err := DB.Where("expires_at < ?", before).Delete(&RefreshToken{}).Error
+	if err != nil {
+		return fmt.Errorf("error pruning refresh tokens: %w", err)
+	}
+	err = DB.Where("expires_at < ?", before).Delete(&RevokedToken{}).Error
+	if err != nil {
+		return fmt.Errorf("error pruning revoked tokens: %w", err)
+	}
+	return nil
+}
+
+// StartPruneSessions creates a goroutine that deletes the expired refresh tokens and revoked tokens every time the
+// given ticker is triggered
+func StartPruneSessions(ticker *time.Ticker) {
+	go func() {
+		for range ticker.C {
+			if err := PruneSessions(time.Now()); err != nil {
+				log.Printf("Error pruning sessions: %v", err)
+			}
+		}
+	}()
+}
+
+// randomToken returns size random bytes encoded as base64 without padding, safe on URLs, headers and cookies
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 of the token. Tokens are random, so a fast hash is enough to keep them secret.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
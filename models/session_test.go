@@ -0,0 +1,140 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+// startSession creates the first refresh token of a new session of the user, and returns the token and the session id
+func startSession(t *testing.T, u models.User, accessJTI string) (string, string) {
+	t.Helper()
+	sessionID, err := models.NewTokenID()
+	if err != nil {
+		t.Fatalf("error generating session id: %v", err)
+	}
+	token, err := u.CreateRefreshToken(sessionID, accessJTI, time.Now().Add(time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("error creating refresh token: %v", err)
+	}
+	return token, sessionID
+}
+
+// assertRevoked fails unless the access tokens with the given ids are revoked
+func assertRevoked(t *testing.T, want bool, jtis ...string) {
+	t.Helper()
+	for _, jti := range jtis {
+		revoked, err := models.IsTokenRevoked(jti)
+		if err != nil {
+			t.Fatalf("error checking revoked token: %v", err)
+		}
+		if revoked != want {
+			t.Fatalf("IsTokenRevoked(%s) = %v, want %v", jti, revoked, want)
+		}
+	}
+}
+
+func TestSpendRefreshTokenRotation(t *testing.T) {
+	dbtest.Open(t)
+	u := createTestUser(t, "user@user.com", models.RoleReader)
+	first, sessionID := startSession(t, u, "access-1")
+
+	// Spending the token returns its session and user
+	rt, spender, err := models.SpendRefreshToken(first)
+	if err != nil {
+		t.Fatalf("error spending refresh token: %v", err)
+	}
+	if rt.SessionID != sessionID || spender.ID != u.ID {
+		t.Fatalf("SpendRefreshToken returned session %s of user %d, want session %s of user %d", rt.SessionID, spender.ID, sessionID, u.ID)
+	}
+
+	// The next token of the session is spent as well
+	second, err := u.CreateRefreshToken(sessionID, "access-2", time.Now().Add(time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("error creating refresh token: %v", err)
+	}
+	if _, _, err := models.SpendRefreshToken(second); err != nil {
+		t.Fatalf("error spending rotated refresh token: %v", err)
+	}
+	assertRevoked(t, false, "access-1", "access-2")
+}
+
+func TestSpendRefreshTokenReuse(t *testing.T) {
+	dbtest.Open(t)
+	u := createTestUser(t, "user@user.com", models.RoleReader)
+	first, sessionID := startSession(t, u, "access-1")
+	if _, _, err := models.SpendRefreshToken(first); err != nil {
+		t.Fatalf("error spending refresh token: %v", err)
+	}
+	second, err := u.CreateRefreshToken(sessionID, "access-2", time.Now().Add(time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("error creating refresh token: %v", err)
+	}
+	other, _ := startSession(t, u, "access-other")
+
+	// Reusing a spent token revokes its whole session, the tokens rotated from it included
+	if _, _, err := models.SpendRefreshToken(first); !errors.Is(err, models.ErrRefreshTokenReused) {
+		t.Fatalf("reusing a spent token returned %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, err := models.SpendRefreshToken(second); !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("spending a token of a revoked session returned %v, want ErrInvalidRefreshToken", err)
+	}
+	assertRevoked(t, true, "access-1", "access-2")
+
+	// Other sessions of the user are left alone
+	assertRevoked(t, false, "access-other")
+	if _, _, err := models.SpendRefreshToken(other); err != nil {
+		t.Fatalf("error spending the token of another session: %v", err)
+	}
+}
+
+func TestSpendRefreshTokenInvalid(t *testing.T) {
+	dbtest.Open(t)
+	u := createTestUser(t, "user@user.com", models.RoleReader)
+
+	if _, _, err := models.SpendRefreshToken("unknown"); !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("spending an unknown token returned %v, want ErrInvalidRefreshToken", err)
+	}
+
+	expired, err := u.CreateRefreshToken("session", "access", time.Now(), -time.Minute)
+	if err != nil {
+		t.Fatalf("error creating refresh token: %v", err)
+	}
+	if _, _, err := models.SpendRefreshToken(expired); !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("spending an expired token returned %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	dbtest.Open(t)
+	u := createTestUser(t, "user@user.com", models.RoleReader)
+	other := createTestUser(t, "other@user.com", models.RoleReader)
+	first, _ := startSession(t, u, "access-1")
+	second, _ := startSession(t, u, "access-2")
+	kept, _ := startSession(t, other, "access-other")
+
+	n, err := u.RevokeSessions()
+	if err != nil || n != 2 {
+		t.Fatalf("RevokeSessions() = %d, %v, want 2, nil", n, err)
+	}
+	for _, token := range []string{first, second} {
+		if _, _, err := models.SpendRefreshToken(token); !errors.Is(err, models.ErrInvalidRefreshToken) {
+			t.Fatalf("spending a token of a revoked session returned %v, want ErrInvalidRefreshToken", err)
+		}
+	}
+	assertRevoked(t, true, "access-1", "access-2")
+
+	// The sessions of other users are left alone
+	assertRevoked(t, false, "access-other")
+	if _, _, err := models.SpendRefreshToken(kept); err != nil {
+		t.Fatalf("error spending the token of another user: %v", err)
+	}
+
+	// Revoking again finds no session
+	if n, err := u.RevokeSessions(); err != nil || n != 0 {
+		t.Fatalf("second RevokeSessions() = %d, %v, want 0, nil", n, err)
+	}
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms of the JWT tokens
+const (
+	// AlgorithmHS256 signs the tokens with the SECRET environment variable
+	AlgorithmHS256 = "HS256"
+	// AlgorithmRS256 signs the tokens with an RSA key
+	AlgorithmRS256 = "RS256"
+	// AlgorithmEdDSA signs the tokens with an Ed25519 key
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// keyTypes are the types of the keys of the asymmetric algorithms
+var keyTypes = map[string]string{
+	AlgorithmRS256: "RSA",
+	AlgorithmEdDSA: "Ed25519",
+}
+
+// MinRSAKeyBits is the minimum size of the RSA keys
+const MinRSAKeyBits = 2048
+
+// SigningKey is an asymmetric key of the JWT tokens, identified by the kid header of the tokens it signs. The id is
+// the JWK thumbprint of the public key (RFC 7638), so it doesn't change while the key is rotated out.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	// Private signs the tokens, nil on the keys that only verify them
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet is the keys of the JWT tokens: the key signing the new tokens and the keys verifying them. With HS256 the
+// tokens are signed with the secret, and the asymmetric keys only verify tokens.
+type KeySet struct {
+	Algorithm string
+	secret    []byte
+	signing   *SigningKey
+	keys      []*SigningKey
+}
+
+// JWK is a public key of the JSON Web Key Set (RFC 7517), with the lowercase members of the standard
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the JSON Web Key Set of the keys verifying the JWT tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Keys is the key set of the JWT tokens, set from the environment on startup
+var Keys = &KeySet{Algorithm: AlgorithmHS256}
+
+// KeySetFromEnv returns the KeySet of the environment variables JWT_ALGORITHM, HS256 by default, JWT_PRIVATE_KEY_FILE,
+// the PEM file of the key signing the tokens with RS256 or EdDSA, and JWT_PUBLIC_KEY_FILES, a comma-separated list of
+// PEM files of the previous keys still verifying tokens. HS256 signs with the SECRET environment variable.
+func KeySetFromEnv() (*KeySet, error) {
+	ks := &KeySet{Algorithm: os.Getenv("JWT_ALGORITHM")}
+	if ks.Algorithm == "" {
+		ks.Algorithm = AlgorithmHS256
+	}
+
+	switch ks.Algorithm {
+	case AlgorithmHS256:
+		ks.secret = []byte(os.Getenv("SECRET"))
+		if len(ks.secret) == 0 {
+			return nil, errors.New("HS256 requires the SECRET environment variable")
+		}
+	case AlgorithmRS256, AlgorithmEdDSA:
+		file := os.Getenv("JWT_PRIVATE_KEY_FILE")
+		if file == "" {
+			return nil, fmt.Errorf("%s requires the JWT_PRIVATE_KEY_FILE environment variable", ks.Algorithm)
+		}
+		key, err := loadKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if key.Private == nil {
+			return nil, fmt.Errorf("error loading signing key %s: it must be a private key", file)
+		}
+		if key.Algorithm != ks.Algorithm {
+			return nil, fmt.Errorf("error loading signing key %s: %s requires an %s key", file, ks.Algorithm, keyTypes[ks.Algorithm])
+		}
+		ks.signing = key
+		ks.keys = append(ks.keys, key)
+	default:
+		return nil, fmt.Errorf("invalid JWT_ALGORITHM %q, must be HS256, RS256 or EdDSA", ks.Algorithm)
+	}
+
+	// Load the keys of the previous tokens, still accepted while they expire
+	for _, file := range strings.Split(os.Getenv("JWT_PUBLIC_KEY_FILES"), ",") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		key, err := loadKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if ks.key(key.ID) == nil {
+			ks.keys = append(ks.keys, &SigningKey{ID: key.ID, Algorithm: key.Algorithm, Public: key.Public})
+		}
+	}
+
+	return ks, nil
+}
+
+// Sign returns the token of the claims, signed with the signing key and its id on the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(ks.signing.Algorithm), claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.Private)
+}
+
+// Keyfunc returns the key verifying the token, the verification key of its kid header. With HS256 the tokens without
+// kid are verified with the secret.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if ks.Algorithm != AlgorithmHS256 {
+			return nil, errors.New("token without key id")
+		}
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return ks.secret, nil
+	}
+
+	key := ks.key(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// JWKS returns the public keys verifying the tokens, the signing key first. HS256 has no public key.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwks.Keys = append(jwks.Keys, jwkOf(key.Public, key.ID))
+	}
+	return jwks
+}
+
+// key returns the verification key with the given id, nil if there is none
+func (ks *KeySet) key(id string) *SigningKey {
+	for _, key := range ks.keys {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// loadKeyFile returns the key of the PEM file, a PKCS #8 or PKCS #1 private key or a PKIX or PKCS #1 public key. The
+// algorithm of the key is RS256 for RSA keys and EdDSA for Ed25519 keys.
+func loadKeyFile(file string) (*SigningKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("error loading key %s: it is not a PEM file", file)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading key %s: %w", file, err)
+	}
+
+	key := &SigningKey{}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = signer
+		key.Public = signer.Public()
+	} else {
+		key.Public = parsed
+	}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < MinRSAKeyBits {
+			return nil, fmt.Errorf("error loading key %s: RSA keys must have at least %d bits", file, MinRSAKeyBits)
+		}
+		key.Algorithm = AlgorithmRS256
+	case ed25519.PublicKey:
+		key.Algorithm = AlgorithmEdDSA
+	default:
+		return nil, fmt.Errorf("error loading key %s: only RSA and Ed25519 keys are supported", file)
+	}
+
+	key.ID = jwkOf(key.Public, "").thumbprint()
+	return key, nil
+}
+
+// jwkOf returns the JWK of the RSA or Ed25519 public key with the given id
+func jwkOf(public crypto.PublicKey, id string) JWK {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Kid: id, Use: "sig", Alg: AlgorithmRS256, N: b64(public.N.Bytes()), E: b64(big.NewInt(int64(public.E)).Bytes())}
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: id, Use: "sig", Alg: AlgorithmEdDSA, Crv: "Ed25519", X: b64(public)}
+	default:
+		return JWK{}
+	}
+}
+
+// thumbprint returns the JWK thumbprint of the key (RFC 7638): the SHA-256 of its required members, in lexicographic
+// order
+func (k JWK) thumbprint() string {
+	var members string
+	if k.Kty == "RSA" {
+		members = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, k.E, k.Kty, k.N)
+	} else {
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, k.Crv, k.Kty, k.X)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKeyFile writes the PKCS #8 private key, or its PKIX public key, to a PEM file and returns its path
+func writeKeyFile(t *testing.T, key interface{}, public bool) string {
+	t.Helper()
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key.(interface{ Public() crypto.PublicKey }).Public())
+		if err != nil {
+			t.Fatalf("error encoding public key: %v", err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("error encoding private key: %v", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "key-*.pem")
+	if err != nil {
+		t.Fatalf("error creating key file: %v", err)
+	}
+	defer file.Close()
+	if err = pem.Encode(file, block); err != nil {
+		t.Fatalf("error writing key file: %v", err)
+	}
+	return file.Name()
+}
+
+func newRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %v", err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating Ed25519 key: %v", err)
+	}
+	return key
+}
+
+// keySetOf returns the KeySet of the given environment variables, the ones not given are unset
+func keySetOf(t *testing.T, env map[string]string) (*KeySet, error) {
+	t.Helper()
+	for _, key := range []string{"JWT_ALGORITHM", "SECRET", "JWT_PRIVATE_KEY_FILE", "JWT_PUBLIC_KEY_FILES"} {
+		t.Setenv(key, env[key])
+	}
+	return KeySetFromEnv()
+}
+
+// mustKeySet returns the KeySet of the given environment variables, failing the test on errors
+func mustKeySet(t *testing.T, env map[string]string) *KeySet {
+	t.Helper()
+	ks, err := keySetOf(t, env)
+	if err != nil {
+		t.Fatalf("KeySetFromEnv() error: %v", err)
+	}
+	return ks
+}
+
+func testKeyClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+// The JWK thumbprint of the example key of RFC 7638, section 3.1
+func TestJWKThumbprint(t *testing.T) {
+	k := JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3o" +
+			"knjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZH" +
+			"zu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEg" +
+			"U8awapJzKnqDKgw",
+	}
+	if got, want := k.thumbprint(), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Fatalf("thumbprint() = %s, want %s", got, want)
+	}
+}
+
+func TestKeySetFromEnv(t *testing.T) {
+	rsaFile := writeKeyFile(t, newRSAKey(t, MinRSAKeyBits), false)
+	rsaPublicFile := writeKeyFile(t, newRSAKey(t, MinRSAKeyBits), true)
+	smallRSA := newRSAKey(t, 1024)
+	edFile := writeKeyFile(t, newEd25519Key(t), false)
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(notPEM, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"HS256 by default", map[string]string{"SECRET": "secret"}, false},
+		{"HS256 without secret", map[string]string{}, true},
+		{"unknown algorithm", map[string]string{"JWT_ALGORITHM": "HS512", "SECRET": "secret"}, true},
+		{"RS256", map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": rsaFile}, false},
+		{"EdDSA", map[string]string{"JWT_ALGORITHM": "EdDSA", "JWT_PRIVATE_KEY_FILE": edFile}, false},
+		{"RS256 without key file", map[string]string{"JWT_ALGORITHM": "RS256"}, true},
+		{"RS256 with an Ed25519 key", map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": edFile}, true},
+		{"EdDSA with an RSA key", map[string]string{"JWT_ALGORITHM": "EdDSA", "JWT_PRIVATE_KEY_FILE": rsaFile}, true},
+		{"signing with a public key", map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": rsaPublicFile}, true},
+		{"missing key file", map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": notPEM + ".missing"}, true},
+		{"key file not PEM", map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": notPEM}, true},
+		// RSA keys below the minimum size are refused, to sign and to verify
+		{"small RSA signing key", map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": writeKeyFile(t, smallRSA, false)}, true},
+		{"small RSA public key", map[string]string{"SECRET": "secret", "JWT_PUBLIC_KEY_FILES": writeKeyFile(t, smallRSA, true)}, true},
+		{"public key files", map[string]string{"JWT_ALGORITHM": "EdDSA", "JWT_PRIVATE_KEY_FILE": edFile, "JWT_PUBLIC_KEY_FILES": " " + rsaPublicFile + ", ,"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keySetOf(t, tt.env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KeySetFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// A retired key verifies the tokens it signed while they expire, but doesn't sign new ones
+func TestKeySetRotation(t *testing.T) {
+	oldKey, newKey := newEd25519Key(t), newRSAKey(t, MinRSAKeyBits)
+	oldFile := writeKeyFile(t, oldKey, false)
+	old := mustKeySet(t, map[string]string{"JWT_ALGORITHM": "EdDSA", "JWT_PRIVATE_KEY_FILE": oldFile})
+	oldToken, err := old.Sign(testKeyClaims())
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+
+	// The private key file of the old key is accepted as a public key file
+	ks := mustKeySet(t, map[string]string{
+		"JWT_ALGORITHM":        "RS256",
+		"JWT_PRIVATE_KEY_FILE": writeKeyFile(t, newKey, false),
+		"JWT_PUBLIC_KEY_FILES": oldFile,
+	})
+	if _, err = jwt.Parse(oldToken, ks.Keyfunc); err != nil {
+		t.Fatalf("token of the retired key rejected: %v", err)
+	}
+
+	newToken, err := ks.Sign(testKeyClaims())
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	token, err := jwt.Parse(newToken, ks.Keyfunc)
+	if err != nil {
+		t.Fatalf("token of the signing key rejected: %v", err)
+	}
+	if token.Method.Alg() != AlgorithmRS256 || token.Header["kid"] != ks.signing.ID {
+		t.Fatalf("token signed with %v by key %v, want RS256 by key %s", token.Method.Alg(), token.Header["kid"], ks.signing.ID)
+	}
+
+	retired := ks.key(old.signing.ID)
+	if retired == nil || retired.Private != nil {
+		t.Fatalf("retired key = %+v, want a key without private key", retired)
+	}
+
+	// The JWKS lists the signing key first, with the thumbprints as ids
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != ks.signing.ID || jwks.Keys[1].Kid != old.signing.ID {
+		t.Fatalf("JWKS() = %+v, want the keys %s and %s", jwks, ks.signing.ID, old.signing.ID)
+	}
+	for _, k := range jwks.Keys {
+		if k.Kid != k.thumbprint() {
+			t.Errorf("key id %s, want its thumbprint %s", k.Kid, k.thumbprint())
+		}
+	}
+}
+
+func TestKeyfuncRejects(t *testing.T) {
+	rsaKey := newRSAKey(t, MinRSAKeyBits)
+	ks := mustKeySet(t, map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": writeKeyFile(t, rsaKey, false)})
+	publicPEM, err := os.ReadFile(writeKeyFile(t, rsaKey, true))
+	if err != nil {
+		t.Fatalf("error reading public key: %v", err)
+	}
+
+	// sign returns the token of the claims signed with the method and key, with the given kid header
+	sign := func(method jwt.SigningMethod, key interface{}, kid string) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, testKeyClaims())
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("error signing token: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// The public key is known by everyone, an HMAC signed with it must not verify
+		{"HS256 signed with the RSA public key", sign(jwt.SigningMethodHS256, publicPEM, ks.signing.ID)},
+		{"HS256 without kid", sign(jwt.SigningMethodHS256, publicPEM, "")},
+		{"EdDSA with the kid of the RSA key", sign(jwt.SigningMethodEdDSA, newEd25519Key(t), ks.signing.ID)},
+		{"unknown kid", sign(jwt.SigningMethodRS256, newRSAKey(t, MinRSAKeyBits), "unknown")},
+		{"RS256 without kid", sign(jwt.SigningMethodRS256, rsaKey, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := jwt.Parse(tt.token, ks.Keyfunc); err == nil {
+				t.Fatal("token accepted, want an error")
+			}
+		})
+	}
+}
+
+// With HS256 the tokens without kid are verified with the secret
+func TestKeyfuncHS256(t *testing.T) {
+	rsaKey := newRSAKey(t, MinRSAKeyBits)
+	ks := mustKeySet(t, map[string]string{"SECRET": "secret", "JWT_PUBLIC_KEY_FILES": writeKeyFile(t, rsaKey, true)})
+
+	token, err := ks.Sign(testKeyClaims())
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	parsed, err := jwt.Parse(token, ks.Keyfunc)
+	if err != nil {
+		t.Fatalf("token of the secret rejected: %v", err)
+	}
+	if _, ok := parsed.Header["kid"]; ok || parsed.Method.Alg() != AlgorithmHS256 {
+		t.Fatalf("token signed with %s and kid %v, want HS256 without kid", parsed.Method.Alg(), parsed.Header["kid"])
+	}
+
+	other, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testKeyClaims()).SignedString([]byte("other"))
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	if _, err = jwt.Parse(other, ks.Keyfunc); err == nil {
+		t.Fatal("token of another secret accepted, want an error")
+	}
+
+	// The asymmetric keys still verify the tokens they signed, and the JWKS has no key of the secret
+	rsaToken := jwt.NewWithClaims(jwt.SigningMethodRS256, testKeyClaims())
+	rsaToken.Header["kid"] = ks.keys[0].ID
+	signed, err := rsaToken.SignedString(rsaKey)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	if _, err = jwt.Parse(signed, ks.Keyfunc); err != nil {
+		t.Fatalf("token of the RSA key rejected: %v", err)
+	}
+	if jwks := ks.JWKS(); len(jwks.Keys) != 1 || jwks.Keys[0].Kid != ks.keys[0].ID {
+		t.Fatalf("JWKS() = %+v, want only the RSA key", jwks)
+	}
+}
@@ -1,12 +1,15 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"log"
 	"net"
 	"os"
+	"sync"
+	"time"
 )
 
 // User is the struct for API users
@@ -15,6 +18,13 @@ type User struct {
 	Email      string              `gorm:"unique" json:"Email,omitempty"`
 	Password   string              `json:"Password,omitempty"`
 	IP         string              `json:"IP,omitempty"`
+	Role       string              `gorm:"size:16;not null;default:reader" json:"Role,omitempty"`
+
+	// Default match options of the user, nil values fall back to DefaultMatchOptions
+	MatchThreshold     *float32 `json:"MatchThreshold,omitempty"`
+	MatchFallback      *bool    `json:"MatchFallback,omitempty"`
+	MatchMaxRelaxation *float32 `json:"MatchMaxRelaxation,omitempty"`
+	MatchAlgorithm     *string  `gorm:"size:32" json:"MatchAlgorithm,omitempty"`
 }
 
 // UserInputBody is the struct for validation middlewares
@@ -23,8 +33,19 @@ type UserInputBody struct {
 	Password string `json:"Password,omitempty"`
 }
 
-// CreateUser creates a new user
+// MatchOptionsInput is the struct for updating the default match options of a user
+type MatchOptionsInput struct {
+	Threshold     *float32 `json:"Threshold,omitempty"`
+	Fallback      *bool    `json:"Fallback,omitempty"`
+	MaxRelaxation *float32 `json:"MaxRelaxation,omitempty"`
+	Algorithm     *string  `json:"Algorithm,omitempty"`
+}
+
+// CreateUser creates a new user. Users without role are created with DefaultRole.
 func (u *User) CreateUser() (User, error) {
+	if u.Role == "" {
+		u.Role = DefaultRole
+	}
 	err := DB.Create(&u)
 	if err.Error != nil {
 		return User{}, fmt.Errorf("error creating userr: %w", err.Error)
@@ -62,13 +83,113 @@ func GetUserByEmail(email string) (User, error) {
 	return getUser, nil
 }
 
-// CreateRoot creates a user directly from the server
+// GetUserByID gets a user by their ID
+func GetUserByID(id uint) (User, error) {
+	var getUser User
+	err := DB.Where("id = ?", id).Find(&getUser)
+	if err.Error != nil {
+		return User{}, fmt.Errorf("error getting user by id: %w", err.Error)
+	}
+	if getUser.ID == 0 {
+		return User{}, fmt.Errorf("error getting user by id: %w", errors.New("user not found on the database"))
+	}
+	return getUser, nil
+}
+
+// MatchOptions returns the default match options of the user
+func (u *User) MatchOptions() MatchOptions {
+	opts := DefaultMatchOptions()
+	if u.MatchThreshold != nil {
+		opts.Threshold = *u.MatchThreshold
+	}
+	if u.MatchFallback != nil {
+		opts.Fallback = *u.MatchFallback
+	}
+	if u.MatchMaxRelaxation != nil {
+		opts.MaxRelaxation = *u.MatchMaxRelaxation
+	}
+	if u.MatchAlgorithm != nil {
+		opts.Algorithm = *u.MatchAlgorithm
+	}
+	return opts
+}
+
+// UpdateMatchOptions validates and saves the default match options of the user. Nil fields of input are left unchanged.
+func (u *User) UpdateMatchOptions(input MatchOptionsInput) (MatchOptions, error) {
+	if input.Threshold != nil {
+		u.MatchThreshold = input.Threshold
+	}
+	if input.Fallback != nil {
+		u.MatchFallback = input.Fallback
+	}
+	if input.MaxRelaxation != nil {
+		u.MatchMaxRelaxation = input.MaxRelaxation
+	}
+	if input.Algorithm != nil {
+		u.MatchAlgorithm = input.Algorithm
+	}
+
+	opts := u.MatchOptions()
+	if err := opts.Validate(); err != nil {
+		return MatchOptions{}, err
+	}
+
+	err := DB.Model(u).Select("MatchThreshold", "MatchFallback", "MatchMaxRelaxation", "MatchAlgorithm").Updates(u).Error
+	if err != nil {
+		return MatchOptions{}, fmt.Errorf("error updating user match options: %w", err)
+	}
+	userMatchOptions.Delete(u.ID)
+
+	return opts, nil
+}
+
+// UserMatchOptionsTTL is how long the default match options of a user are cached. An update applies at once on the
+// instance that saved it and once the cached options expire on the others.
+const UserMatchOptionsTTL = time.Minute
+
+// userMatchOptions caches the default match options by user id, so the match requests don't read the user table
+var userMatchOptions sync.Map
+
+// cachedMatchOptions is an entry of userMatchOptions
+type cachedMatchOptions struct {
+	opts      MatchOptions
+	expiresAt time.Time
+}
+
+// UserMatchOptions returns the default match options of the user with the given id, read from the database only when
+// they are not cached or the cached ones expired
+func UserMatchOptions(id uint) (MatchOptions, error) {
+	if entry, ok := userMatchOptions.Load(id); ok {
+		if cached := entry.(cachedMatchOptions); time.Now().Before(cached.expiresAt) {
+			return cached.opts, nil
+		}
+	}
+
+	u, err := GetUserByID(id)
+	if err != nil {
+		return MatchOptions{}, err
+	}
+	opts := u.MatchOptions()
+	userMatchOptions.Store(id, cachedMatchOptions{opts: opts, expiresAt: time.Now().Add(UserMatchOptionsTTL)})
+	return opts, nil
+}
+
+// RootUserID is the id of the root user created by CreateRoot
+const RootUserID = 1
+
+// CreateRoot creates a user directly from the server, with the admin role
 func CreateRoot() error {
 	var user User
-	DB.Raw("select * from users where id = 1").Find(&user)
+	DB.Where("id = ?", RootUserID).Limit(1).Find(&user)
 
 	if user.ID == 0 {
-		hash, err := bcrypt.GenerateFromPassword([]byte(os.Getenv("SECRET")), 10)
+		// The SECRET is the password of the root user, also when the tokens are not signed with it
+		secret := os.Getenv("SECRET")
+		if secret == "" {
+			return errors.New("error creating user root: the SECRET environment variable is required")
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), 10)
 		if err != nil {
 			return fmt.Errorf("error hashing the password on create root: %w", err)
 		}
@@ -82,6 +203,7 @@ func CreateRoot() error {
 			Email:    "root@root.com",
 			Password: string(hash),
 			IP:       ip,
+			Role:     RoleAdmin,
 		}
 
 		_, err = userRoot.CreateUser()
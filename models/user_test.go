@@ -0,0 +1,43 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/Darklabel91/API_Names/database/dbtest"
+	"github.com/Darklabel91/API_Names/models"
+)
+
+// The match options of a user are read once and cached, an update replaces them at once
+func TestUserMatchOptionsCache(t *testing.T) {
+	db := dbtest.Open(t)
+	u := createTestUser(t, "user@test.com", models.RoleReader)
+
+	opts, err := models.UserMatchOptions(u.ID)
+	if err != nil || opts != models.DefaultMatchOptions() {
+		t.Fatalf("UserMatchOptions() = %+v, %v, want the defaults", opts, err)
+	}
+
+	// A change on the table, like one of another instance, is not read while the options are cached
+	if err = db.Model(&models.User{}).Where("id = ?", u.ID).Update("match_threshold", 0.5).Error; err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	if opts, err = models.UserMatchOptions(u.ID); err != nil || opts != models.DefaultMatchOptions() {
+		t.Fatalf("UserMatchOptions() after a change on the table = %+v, %v, want the cached defaults", opts, err)
+	}
+
+	// An update of the instance invalidates the cached options
+	threshold, fallback := float32(0.7), false
+	if _, err = u.UpdateMatchOptions(models.MatchOptionsInput{Threshold: &threshold, Fallback: &fallback}); err != nil {
+		t.Fatalf("error updating match options: %v", err)
+	}
+	want := models.DefaultMatchOptions()
+	want.Threshold, want.Fallback = threshold, fallback
+	if opts, err = models.UserMatchOptions(u.ID); err != nil || opts != want {
+		t.Fatalf("UserMatchOptions() after the update = %+v, %v, want %+v", opts, err, want)
+	}
+
+	// Unknown users have no options
+	if _, err = models.UserMatchOptions(99); err == nil {
+		t.Fatal("UserMatchOptions() of an unknown user succeeded, want an error")
+	}
+}
@@ -2,9 +2,10 @@ package routes
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"os"
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/Darklabel91/API_Names/controllers"
@@ -16,6 +17,9 @@ import (
 const DOOR = ":8080"
 const FILENAME = "Logs.txt"
 const MICROSECONDS = 300
+const CacheVersionHeader = "X-Cache-Version"
+const DefaultCacheSyncInterval = 5 * time.Second
+const ChangeRetention = 24 * time.Hour
 
 func HandleRequests() error {
 	// Set Gin to release mode.
@@ -40,14 +44,27 @@ func HandleRequests() error {
 	r.Use(gin.LoggerWithWriter(file))
 
 	// Upload the log file from time to time.
-	var log models.Log
+	var logs models.Log
 	ticker := time.NewTicker(MICROSECONDS * time.Microsecond)
 	defer ticker.Stop()
-	log.UploadLog(ticker, FILENAME)
+	logs.UploadLog(ticker, FILENAME)
+
+	// Load the name cache before the server starts listening.
+	cache := models.NewNameCache(models.DBNameSource{})
+	snapshot, err := cache.Snapshot()
+	if err != nil {
+		return fmt.Errorf("error preloading name cache: %w", err)
+	}
+	log.Printf("-	Name cache loaded with %d names", snapshot.Names)
 
 	// Routes without middleware.
 	r.POST("/signup", controllers.Signup)
 	r.POST("/login", controllers.Login)
+	r.POST("/token/refresh", controllers.RefreshToken)
+	r.POST("/logout", controllers.Logout)
+	r.GET("/healthz", controllers.Healthz)
+	r.GET("/.well-known/jwks.json", controllers.JWKS)
+	r.GET("/readyz", withNameCache(cache), controllers.Readyz)
 
 	// Main middleware validation.
 	r.Use(middlewares.ValidateAuth())
@@ -56,16 +73,65 @@ func HandleRequests() error {
 	r.Use(middlewares.RateLimit())
 
 	// Cache the name types.
-	cache := &sync.Map{}
 	r.Use(cachingNameTypes(cache))
 
-	// CRUD routes.
-	r.POST("/name", middlewares.ValidateNameJSON(), controllers.CreateName)
+	// Apply the changes of other instances from the change log from time to time.
+	syncInterval, err := cacheSyncInterval()
+	if err != nil {
+		return err
+	}
+	syncTicker := time.NewTicker(syncInterval)
+	defer syncTicker.Stop()
+	cache.StartSync(syncTicker)
+
+	// Prune the change log from time to time.
+	pruneTicker := time.NewTicker(time.Hour)
+	defer pruneTicker.Stop()
+	models.StartPruneNameChanges(pruneTicker, ChangeRetention)
+
+	// Prune the expired refresh tokens and revoked tokens from time to time.
+	sessionTicker := time.NewTicker(time.Hour)
+	defer sessionTicker.Stop()
+	models.StartPruneSessions(sessionTicker)
+
+	// Read routes, open to every role.
 	r.GET("/:id", middlewares.ValidateID(), controllers.GetID)
 	r.GET("/name/:name", middlewares.ValidateName(), controllers.GetName)
-	r.GET("/metaphone/:name", middlewares.ValidateName(), controllers.GetMetaphoneMatch)
-	r.PATCH("/:id", middlewares.ValidateID(), middlewares.ValidateNameJSON(), controllers.UpdateName)
-	r.DELETE("/:id", middlewares.ValidateID(), controllers.DeleteName)
+	r.GET("/variation/:name", middlewares.ValidateName(), controllers.GetVariation)
+	r.GET("/metaphone/:name", middlewares.ValidateName(), middlewares.ValidateTop(), middlewares.ValidateExplain(), middlewares.ValidateMatchOptions(), controllers.GetMetaphoneMatch)
+	r.POST("/metaphone/batch", middlewares.ValidateBatchJSON(), middlewares.ValidateMatchOptions(), controllers.GetBatchMetaphoneMatch)
+	r.GET("/gender/:name", middlewares.ValidateName(), middlewares.ValidateMatchOptions(), controllers.GetGender)
+	r.POST("/gender/batch", middlewares.ValidateBatchJSON(), middlewares.ValidateMatchOptions(), controllers.GetBatchGender)
+	r.GET("/export", middlewares.ValidateExport(), controllers.ExportNames)
+	r.GET("/names", middlewares.ValidateNameList(), controllers.ListNames)
+
+	// Write routes, restricted to editors.
+	editor := r.Group("/", middlewares.RequireRole(models.RoleEditor))
+	editor.POST("/name", middlewares.ValidateNameJSON(), controllers.CreateName)
+	editor.PATCH("/:id", middlewares.ValidateID(), middlewares.ValidateNameJSON(), controllers.UpdateName)
+	editor.DELETE("/:id", middlewares.ValidateID(), controllers.DeleteName)
+	editor.POST("/name/:id/variations", middlewares.ValidateID(), middlewares.ValidateVariationsJSON(), controllers.AddVariations)
+	editor.DELETE("/name/:id/variations", middlewares.ValidateID(), middlewares.ValidateVariationsJSON(), controllers.DeleteVariations)
+
+	// User routes.
+	r.GET("/user/options", controllers.GetUserMatchOptions)
+	r.PATCH("/user/options", controllers.UpdateUserMatchOptions)
+
+	// API key routes, restricted to logged in users.
+	keys := r.Group("/user/keys", middlewares.RequireSession())
+	keys.POST("", middlewares.ValidateAPIKeyJSON(), controllers.CreateAPIKey)
+	keys.GET("", controllers.GetAPIKeys)
+	keys.DELETE("/:id", middlewares.ValidateID(), controllers.RevokeAPIKey)
+
+	// Admin routes, restricted to admins.
+	admin := r.Group("/admin", middlewares.RequireRole(models.RoleAdmin))
+	admin.GET("/cache", controllers.GetCache)
+	admin.POST("/cache/reload", controllers.ReloadCache)
+	admin.POST("/import", middlewares.ValidateImport(), controllers.ImportNames)
+	admin.GET("/users", controllers.GetUserRoles)
+	admin.PUT("/users/:id/role", middlewares.ValidateID(), middlewares.ValidateRoleJSON(), controllers.GrantRole)
+	admin.DELETE("/users/:id/role", middlewares.ValidateID(), controllers.RevokeRole)
+	admin.DELETE("/users/:id/sessions", middlewares.ValidateID(), controllers.RevokeUserSessions)
 
 	// Start the server.
 	err = r.Run(DOOR)
@@ -76,22 +142,43 @@ func HandleRequests() error {
 	return nil
 }
 
-// Caches the name types.
-func cachingNameTypes(cache *sync.Map) gin.HandlerFunc {
+// cacheSyncInterval returns the interval between the syncs of the name cache, set by the optional CACHE_SYNC_INTERVAL
+// environment variable as a duration such as 5s or 1m.
+func cacheSyncInterval() (time.Duration, error) {
+	value := os.Getenv("CACHE_SYNC_INTERVAL")
+	if value == "" {
+		return DefaultCacheSyncInterval, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("error parsing CACHE_SYNC_INTERVAL %q: must be a positive duration such as 5s", value)
+	}
+	return interval, nil
+}
+
+// withNameCache passes the name cache to the handlers without loading it.
+func withNameCache(cache *models.NameCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Check the cache.
-		cacheData, existKey := cache.Load("nameTypes")
-		if existKey {
-			c.Set("nameTypes", cacheData)
-		} else {
-			allNames, err := models.GetAllNames()
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"Message": "Error on caching all name types"})
-				return
-			}
-			cache.Store("nameTypes", allNames)
-			c.Set("nameTypes", allNames)
+		c.Set("nameCache", cache)
+		c.Next()
+	}
+}
+
+// Caches the name types index. Every request reads a single snapshot of the cache, its version is echoed back on the
+// X-Cache-Version header.
+func cachingNameTypes(cache *models.NameCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the current snapshot, loading it if the cache is empty.
+		snapshot, err := cache.Snapshot()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"Message": "Error on caching all name types"})
+			return
 		}
+
+		c.Header(CacheVersionHeader, strconv.FormatUint(snapshot.Version, 10))
+		c.Set("nameCache", cache)
+		c.Set("nameTypes", snapshot.Index)
 		c.Next()
 	}
 }